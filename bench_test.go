@@ -0,0 +1,134 @@
+package nvim_test
+
+import (
+	"fmt"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+const benchRows, benchCols = 100, 300
+
+// Returns a widget with a large grid and a few highlights, as after starting
+// nvim in a maximized window
+func benchNeoVim(b *testing.B) *nvim.NeoVim {
+	b.Helper()
+
+	n := nvim.NewUnattached()
+	n.HandleNvimEvent(nvimtest.GridResize(benchCols, benchRows))
+	for id := 1; id <= 8; id++ {
+		n.HandleNvimEvent(nvimtest.HLAttrDefine(id, map[string]interface{}{
+			"foreground": int64(id * 0x101010),
+			"bold":       id%2 == 0,
+		}))
+	}
+
+	return n
+}
+
+// Returns grid_line events redrawing the whole grid, changing the highlight
+// every few cells like syntax highlighting does
+func fullRedraw() [][]interface{} {
+	events := make([][]interface{}, benchRows)
+	for row := range events {
+		cells := make([][]interface{}, 0, benchCols)
+		for col := 0; col < benchCols; col++ {
+			text := string(rune('a' + (row+col)%26))
+			if col%5 == 0 {
+				cells = append(cells, nvimtest.Cell(text, col/5%9))
+			} else {
+				cells = append(cells, nvimtest.Cell(text))
+			}
+		}
+		events[row] = nvimtest.GridLine(row, 0, cells...)
+	}
+
+	return events
+}
+
+func BenchmarkGridLine(b *testing.B) {
+	n := benchNeoVim(b)
+	events := fullRedraw()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, e := range events {
+			n.HandleNvimEvent(e)
+		}
+	}
+}
+
+// Returns the events nvim sends to scroll a line down, as on <C-E>
+func scrollLine() (scroll, line []interface{}) {
+	cells := make([][]interface{}, 0, benchCols)
+	for col := 0; col < benchCols; col++ {
+		cells = append(cells, nvimtest.Cell(fmt.Sprint(col%10), col/5%9))
+	}
+
+	return nvimtest.GridScroll(0, benchRows-2, 0, benchCols, 1),
+		nvimtest.GridLine(benchRows-3, 0, cells...)
+}
+
+func BenchmarkScroll(b *testing.B) {
+	n := benchNeoVim(b)
+	for _, e := range fullRedraw() {
+		n.HandleNvimEvent(e)
+	}
+	scroll, line := scrollLine()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.HandleNvimEvent(scroll)
+		n.HandleNvimEvent(line)
+	}
+}
+
+// Returns a widget like benchNeoVim, which also renders every flush
+func benchRenderedNeoVim(b *testing.B) *nvim.NeoVim {
+	b.Helper()
+
+	test.NewApp()
+	b.Cleanup(func() { test.NewApp() })
+
+	n := benchNeoVim(b)
+	test.WidgetRenderer(n)
+	for _, e := range fullRedraw() {
+		n.HandleNvimEvent(e)
+	}
+	n.HandleNvimEvent(nvimtest.Flush())
+
+	return n
+}
+
+// Typing a character in insert mode, which changes a cell and moves the cursor
+func BenchmarkTyping(b *testing.B) {
+	n := benchRenderedNeoVim(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		col := i % (benchCols - 1)
+		n.HandleNvimEvent(nvimtest.GridLine(50, col, nvimtest.Cell("x", 1)))
+		n.HandleNvimEvent(nvimtest.GridCursorGoto(50, col+1))
+		n.HandleNvimEvent(nvimtest.Flush())
+	}
+}
+
+// Scrolling a line at a time, e.g. holding <C-E>
+func BenchmarkScrollFlush(b *testing.B) {
+	n := benchRenderedNeoVim(b)
+
+	scroll, line := scrollLine()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.HandleNvimEvent(scroll)
+		n.HandleNvimEvent(line)
+		n.HandleNvimEvent(nvimtest.Flush())
+	}
+}
@@ -0,0 +1,163 @@
+package nvim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Sessions are recorded in the asciicast v2 format of asciinema, see
+// https://docs.asciinema.org/manual/asciicast/v2/
+// It consists of a JSON header followed by one JSON array per line for every
+// output: [seconds since the start, "o", text with ANSI escape sequences]
+
+// Writes the frames flushed by nvim as asciicast
+type castRecorder struct {
+	w     io.Writer
+	start time.Time
+	prev  Snapshot // the last recorded frame, empty before the first one
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env"`
+}
+
+// Starts recording what the widget shows as asciicast v2 to w, starting with
+// the next screen update. Any previous recording is replaced. The file can be
+// played with e.g. "asciinema play".
+func (n *NeoVim) StartRecording(w io.Writer) {
+	n.recorderMu.Lock()
+	defer n.recorderMu.Unlock()
+
+	n.cast = &castRecorder{w: w, start: time.Now()}
+}
+
+// Stops the recording started with StartRecording. The writer is left to the
+// caller to close.
+func (n *NeoVim) StopRecording() {
+	n.recorderMu.Lock()
+	defer n.recorderMu.Unlock()
+
+	n.cast = nil
+}
+
+// Adds the current screen as frame to the recording, if there is one.
+// Recording is stopped if writing fails.
+func (n *NeoVim) recordFrame() {
+	n.recorderMu.Lock()
+	defer n.recorderMu.Unlock()
+
+	if n.cast == nil {
+		return
+	}
+
+	err := n.cast.frame(n.Snapshot())
+	if err != nil {
+		n.log().Error("recording frame failed, stopped recording", "err", err)
+		n.cast = nil
+	}
+}
+
+// Writes the rows which differ from the previous frame, or everything for the
+// first frame and after the grid got resized
+func (rec *castRecorder) frame(s Snapshot) error {
+	cols, rows := 0, len(s.Cells)
+	if rows > 0 {
+		cols = len(s.Cells[0])
+	}
+	prevCols, prevRows := 0, len(rec.prev.Cells)
+	if prevRows > 0 {
+		prevCols = len(rec.prev.Cells[0])
+	}
+
+	first := rec.prev.Cells == nil
+	// players reject a terminal without a size, so the recording starts once
+	// nvim sized the grid
+	if first && (cols == 0 || rows == 0) {
+		return nil
+	}
+	if first {
+		err := json.NewEncoder(rec.w).Encode(castHeader{
+			Version:   2,
+			Width:     cols,
+			Height:    rows,
+			Timestamp: rec.start.Unix(),
+			Env:       map[string]string{"TERM": "xterm-256color"},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	resized := cols != prevCols || rows != prevRows
+	if resized && !first {
+		err := rec.event("r", fmt.Sprintf("%dx%d", cols, rows))
+		if err != nil {
+			return err
+		}
+	}
+	if resized {
+		b.WriteString("\x1b[0m\x1b[2J")
+	}
+
+	for i, row := range s.Cells {
+		if !resized && rowsEqual(row, rec.prev.Cells[i]) {
+			continue
+		}
+		fmt.Fprintf(&b, "\x1b[%d;1H%s\x1b[0m", i+1, rowANSI(row))
+	}
+
+	if b.Len() == 0 && s.CursorRow == rec.prev.CursorRow &&
+		s.CursorCol == rec.prev.CursorCol && s.CursorShape == rec.prev.CursorShape {
+		return nil
+	}
+
+	// move the terminal's cursor to where nvim has it, in the same shape
+	fmt.Fprintf(&b, "\x1b[%d;%dH\x1b[%d q", s.CursorRow+1, s.CursorCol+1,
+		cursorShapeDECSCUSR(s.CursorShape))
+
+	rec.prev = s
+	return rec.event("o", b.String())
+}
+
+// Writes a single event of the given type
+func (rec *castRecorder) event(typ string, data string) error {
+	line, err := json.Marshal([]interface{}{time.Since(rec.start).Seconds(), typ, data})
+	if err != nil {
+		return err
+	}
+
+	_, err = rec.w.Write(append(line, '\n'))
+	return err
+}
+
+func rowsEqual(a, b []SnapshotCell) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the parameter of the DECSCUSR sequence for a steady cursor shape
+func cursorShapeDECSCUSR(shape string) int {
+	switch shape {
+	case "horizontal":
+		return 4
+	case "vertical":
+		return 6
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,98 @@
+package nvim_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+// Splits a recording into its header and events
+func parseCast(t *testing.T, cast string) (map[string]interface{}, [][]interface{}) {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSuffix(cast, "\n"), "\n")
+
+	var header map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+
+	events := make([][]interface{}, len(lines)-1)
+	for i, line := range lines[1:] {
+		assert.NoError(t, json.Unmarshal([]byte(line), &events[i]))
+	}
+
+	return header, events
+}
+
+func TestRecording(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	var buf bytes.Buffer
+	n.StartRecording(&buf)
+	drawScene(peer)
+
+	// only the changed row and the cursor are written
+	peer.Redraw(
+		nvimtest.GridLine(2, 0, nvimtest.Cells("new", 0)...),
+		nvimtest.Flush(),
+	)
+
+	// nothing changed, so nothing is written
+	peer.Redraw(nvimtest.Flush())
+
+	peer.Redraw(
+		nvimtest.GridResize(20, 4),
+		nvimtest.Flush(),
+	)
+
+	n.StopRecording()
+	peer.Redraw(
+		nvimtest.GridLine(2, 0, nvimtest.Cells("old", 0)...),
+		nvimtest.Flush(),
+	)
+
+	header, events := parseCast(t, buf.String())
+	assert.Equal(t, 2.0, header["version"])
+	assert.Equal(t, 16.0, header["width"])
+	assert.Equal(t, 4.0, header["height"])
+
+	if !assert.Len(t, events, 4) {
+		return
+	}
+
+	first := events[0][2].(string)
+	assert.Equal(t, "o", events[0][1])
+	assert.True(t, strings.HasPrefix(first, "\x1b[0m\x1b[2J"), "clears the screen: %q", first)
+	assert.Contains(t, first, "\x1b[1;1H")
+	assert.Contains(t, first, "\x1b[4;1H")
+	assert.True(t, strings.HasSuffix(first, "\x1b[2;5H\x1b[6 q"), "moves the cursor: %q", first)
+
+	second := events[1][2].(string)
+	assert.True(t, strings.HasPrefix(second, "\x1b[3;1H"), "only writes row 3: %q", second)
+	assert.Contains(t, second, "new")
+	assert.NotContains(t, second, "\x1b[1;1H")
+
+	assert.Equal(t, []interface{}{"r", "20x4"}, events[2][1:])
+	assert.Contains(t, events[3][2], "\x1b[2J")
+
+	assert.LessOrEqual(t, events[0][0], events[3][0])
+	assert.NotContains(t, buf.String(), "old")
+}
+
+func TestRecordingBeforeResize(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	var buf bytes.Buffer
+	n.StartRecording(&buf)
+	peer.Redraw(nvimtest.Flush())
+	assert.Empty(t, buf.String())
+
+	drawScene(peer)
+	header, events := parseCast(t, buf.String())
+	assert.Equal(t, 16.0, header["width"])
+	assert.Equal(t, 4.0, header["height"])
+	assert.Len(t, events, 1)
+}
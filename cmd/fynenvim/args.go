@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	nvim "github.com/yesoer/fyne-nvim"
+)
+
+const usage = `Usage: fynenvim [options] [file ...] [+cmd ...] [-- nvim-args ...]
+
+Files and +cmd arguments are opened just like nvim would, everything after
+"--" is handed to nvim unchanged (e.g. -- -u NONE or -- --clean).
+
+Options:
+`
+
+// config holds everything parsed from the command line
+type config struct {
+	cwd         string
+	geometry    fyne.Size
+	fullscreen  bool
+	maximized   bool
+	nvimPath    string
+	server      string
+	wait        bool
+	newWindow   bool
+	fontSize    float64
+	padding     float64
+	center      bool
+	scrollAnim  time.Duration
+	cursorAnim  time.Duration
+	cursorEase  fyne.AnimationCurve
+	cursorTrail bool
+	unfocused   nvim.UnfocusedCursor
+	notifyFocus bool
+	ligatures   bool
+	features    []string
+	fallbacks   []fyne.Resource
+	emojiFont   fyne.Resource
+	session     bool
+	record      string
+
+	// debugging the rendering
+	logLevel      nvim.LogLevel
+	recordRedraws string
+	replay        string
+
+	// names of the flags given explicitly, which take precedence over
+	// anything restored from the preferences
+	set map[string]bool
+
+	// files and +cmd arguments in the order they were given
+	positional []string
+	// arguments following "--" which are passed to nvim as they are
+	passthrough []string
+}
+
+// Parses the command line arguments (without the program name). Flags and
+// positional arguments may be mixed, "--" ends the fynenvim arguments.
+func parseArgs(args []string, output io.Writer) (*config, error) {
+	cfg := &config{set: make(map[string]bool)}
+
+	fs := flag.NewFlagSet("fynenvim", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.cwd, "cwd", "./", "working directory for nvim")
+	cfg.geometry = defaultGeometry
+	fs.Func("geometry", "initial window size as WxH, defaults to the last one used or 900x600", func(s string) error {
+		size, err := parseGeometry(s)
+		cfg.geometry = size
+		return err
+	})
+	fs.BoolVar(&cfg.fullscreen, "fullscreen", false, "start in fullscreen, defaults to the last state used")
+	fs.BoolVar(&cfg.maximized, "maximized", false, "start with a window covering the screen")
+	fs.StringVar(&cfg.nvimPath, "nvim", "", "path to the nvim executable")
+	fs.StringVar(&cfg.server, "server", "", "address of a running nvim to attach to (see :help --listen)")
+	fs.BoolVar(&cfg.wait, "wait", false, "stay in the foreground until nvim exits and return its exit code")
+	fs.BoolVar(&cfg.newWindow, "new-window", false, "start an independent instance instead of opening the files in a running one")
+	fs.Float64Var(&cfg.fontSize, "font-size", 0, "text size, defaults to the last one used or the theme's")
+	fs.Float64Var(&cfg.padding, "padding", 0, "space around the grid, drawn in nvim's background")
+	fs.BoolVar(&cfg.center, "center", false, "center the grid, leaving the same space at the left and right, top and bottom")
+	fs.DurationVar(&cfg.scrollAnim, "scroll-animation", 0, "how long scrolling glides to the new lines, e.g. 150ms")
+	fs.DurationVar(&cfg.cursorAnim, "cursor-animation", 0, "how long the cursor slides to where it moved, e.g. 100ms")
+	fs.Func("cursor-easing", "how the cursor slides: linear, ease-in, ease-out or ease-in-out", func(s string) error {
+		curve, err := parseEasing(s)
+		cfg.cursorEase = curve
+		return err
+	})
+	fs.BoolVar(&cfg.cursorTrail, "cursor-trail", false, "draw a trail behind the sliding cursor")
+	fs.Func("unfocused-cursor", "how the cursor looks without the focus: hollow (default), dimmed, hidden or unchanged", func(s string) error {
+		look, err := parseUnfocusedCursor(s)
+		cfg.unfocused = look
+		return err
+	})
+	fs.BoolVar(&cfg.notifyFocus, "notify-focus", false, "trigger the FocusGained and FocusLost autocommands")
+	fs.BoolVar(&cfg.ligatures, "ligatures", false, "join e.g. -> into a single glyph, if the font has ligatures")
+	fs.Func("font-features", "comma separated OpenType features to shape the text with, e.g. ss01,-calt", func(s string) error {
+		cfg.features = strings.Split(s, ",")
+		return nil
+	})
+	fs.Func("fallback-font", "`file` of a font for glyphs missing from the monospace one, may be repeated", func(s string) error {
+		res, err := fyne.LoadResourceFromPath(s)
+		cfg.fallbacks = append(cfg.fallbacks, res)
+		return err
+	})
+	fs.Func("emoji-font", "`file` of a color emoji font, e.g. Noto Color Emoji", func(s string) error {
+		res, err := fyne.LoadResourceFromPath(s)
+		cfg.emojiFont = res
+		return err
+	})
+	fs.BoolVar(&cfg.session, "session", false, "restore the session of the working directory and save it on exit")
+	fs.StringVar(&cfg.record, "record", "", "record the session as asciicast to `file`, e.g. for asciinema play")
+	cfg.logLevel = nvim.LogInfo
+	fs.Func("log-level", "least severe messages to print: debug, info, warn or error", func(s string) error {
+		level, err := parseLogLevel(s)
+		cfg.logLevel = level
+		return err
+	})
+	fs.StringVar(&cfg.recordRedraws, "record-redraws", "", "write every redraw batch received from nvim to `file`")
+	fs.StringVar(&cfg.replay, "replay", "", "show the redraws recorded in `file` instead of starting nvim")
+
+	// split off the arguments meant for nvim
+	for i, arg := range args {
+		if arg == "--" {
+			cfg.passthrough = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+
+	// the flag package stops at the first positional argument, so keep going
+	// until everything is consumed to allow e.g. "fynenvim file.go --cwd .."
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		cfg.positional = append(cfg.positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	fs.Visit(func(f *flag.Flag) { cfg.set[f.Name] = true })
+
+	// the cwd may be handed to a nvim running elsewhere
+	if abs, err := filepath.Abs(cfg.cwd); err == nil {
+		cfg.cwd = abs
+	}
+
+	return cfg, nil
+}
+
+// Parses a window size given as WxH e.g. 900x600
+func parseGeometry(s string) (fyne.Size, error) {
+	invalid := errors.New("expected WxH e.g. 900x600")
+	width, height, ok := strings.Cut(strings.ToLower(s), "x")
+	if !ok {
+		return fyne.Size{}, invalid
+	}
+	w, err := strconv.Atoi(width)
+	if err != nil || w <= 0 {
+		return fyne.Size{}, invalid
+	}
+	h, err := strconv.Atoi(height)
+	if err != nil || h <= 0 {
+		return fyne.Size{}, invalid
+	}
+
+	return fyne.NewSize(float32(w), float32(h)), nil
+}
+
+// Parses the name of an animation curve as used in CSS
+func parseEasing(s string) (fyne.AnimationCurve, error) {
+	switch strings.ToLower(s) {
+	case "linear":
+		return fyne.AnimationLinear, nil
+	case "ease-in":
+		return fyne.AnimationEaseIn, nil
+	case "ease-out":
+		return fyne.AnimationEaseOut, nil
+	case "ease-in-out":
+		return fyne.AnimationEaseInOut, nil
+	}
+	return nil, errors.New("expected linear, ease-in, ease-out or ease-in-out")
+}
+
+// Parses how the cursor looks while the window doesn't have the focus
+func parseUnfocusedCursor(s string) (nvim.UnfocusedCursor, error) {
+	switch strings.ToLower(s) {
+	case "hollow":
+		return nvim.CursorHollow, nil
+	case "dimmed":
+		return nvim.CursorDimmed, nil
+	case "hidden":
+		return nvim.CursorHidden, nil
+	case "unchanged":
+		return nvim.CursorUnchanged, nil
+	}
+	return nvim.CursorHollow, errors.New("expected hollow, dimmed, hidden or unchanged")
+}
+
+// Parses the name of a log level, e.g. debug to see every event nvim sends
+func parseLogLevel(s string) (nvim.LogLevel, error) {
+	for _, level := range []nvim.LogLevel{nvim.LogDebug, nvim.LogInfo, nvim.LogWarn, nvim.LogError} {
+		if strings.EqualFold(s, level.String()) {
+			return level, nil
+		}
+	}
+
+	return nvim.LogInfo, errors.New("expected debug, info, warn or error")
+}
+
+// Returns the arguments to start an embedded nvim with
+func (cfg *config) nvimArgs() []string {
+	// options go first, as nvim treats everything after "--" as a file
+	args := make([]string, 0, len(cfg.positional)+len(cfg.passthrough))
+	args = append(args, cfg.passthrough...)
+	return append(args, cfg.positional...)
+}
+
+// Returns the options of the widget, the same whether it shows a running nvim
+// or a replay
+func (cfg *config) options() nvim.Options {
+	return nvim.Options{
+		Command: cfg.nvimPath,
+		Args:    cfg.nvimArgs(),
+		Server:  cfg.server,
+		Logger:  nvim.NewTextLogger(os.Stderr, cfg.logLevel),
+
+		Padding:         float32(cfg.padding),
+		CenterGrid:      cfg.center,
+		ScrollAnimation: cfg.scrollAnim,
+		CursorAnimation: cfg.cursorAnim,
+		CursorEasing:    cfg.cursorEase,
+		CursorTrail:     cfg.cursorTrail,
+		UnfocusedCursor: cfg.unfocused,
+		NotifyFocus:     cfg.notifyFocus,
+		Ligatures:       cfg.ligatures,
+		FontFeatures:    cfg.features,
+		FallbackFonts:   cfg.fallbacks,
+		EmojiFont:       cfg.emojiFont,
+	}
+}
+
+// Whether the files may be opened by an already running instance. Anything
+// affecting how nvim is started requires a new instance.
+func (cfg *config) forwardable() bool {
+	return !cfg.newWindow && cfg.server == "" && cfg.nvimPath == "" &&
+		len(cfg.passthrough) == 0 && cfg.record == "" && cfg.recordRedraws == "" &&
+		cfg.replay == ""
+}
+
+// Exits with the usage status if the arguments could not be parsed
+func mustParseArgs() *config {
+	cfg, err := parseArgs(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	return cfg
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Set in the environment of the background process started by detach
+const detachedEnv = "FYNENVIM_DETACHED"
+
+// Like other GUI editors fynenvim returns immediately unless --wait is given,
+// by restarting itself in the background. Returns false if we already are the
+// background process and should run the application.
+func detach() bool {
+	if os.Getenv(detachedEnv) != "" {
+		return false
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return false
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), detachedEnv+"=1")
+	cmd.SysProcAttr = detachedProcAttr
+	err = cmd.Start()
+	if err != nil {
+		// fall back to running in the foreground
+		return false
+	}
+
+	cmd.Process.Release()
+	return true
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+// Start a new session so the process survives the terminal being closed
+var detachedProcAttr = &syscall.SysProcAttr{Setsid: true}
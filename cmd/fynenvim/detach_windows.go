@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// DETACHED_PROCESS so the process does not share the console
+var detachedProcAttr = &syscall.SysProcAttr{CreationFlags: 0x00000008}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	nvim "github.com/yesoer/fyne-nvim"
+)
+
+// The notification :FynenvimExport sends us with the file to export to
+const exportMethod = "fynenvim_export"
+
+// Adds the :FynenvimExport {file} command to nvim, which writes the screen to
+// file. The format is chosen by the extension: .png, .html/.htm or else ANSI
+// text.
+func setupExport(n *nvim.NeoVim) error {
+	err := n.Engine.RegisterHandler(exportMethod, func(pth string) {
+		err := exportScreen(n, pth)
+		if err != nil {
+			notify(n, fmt.Sprintf("Error exporting screen: %v", err), "ERROR")
+			return
+		}
+		notify(n, "Exported screen to "+pth, "INFO")
+	})
+	if err != nil {
+		return err
+	}
+
+	// the command line would still show the command, so it is cleared and the
+	// screen redrawn before we're asked to export it
+	return n.Engine.ExecLua(`
+		local chan = ...
+		vim.api.nvim_create_user_command('FynenvimExport', function(opts)
+			local pth = vim.fn.fnamemodify(vim.fn.expand(opts.args), ':p')
+			vim.schedule(function()
+				vim.cmd('echo "" | redraw')
+				vim.rpcnotify(chan, '`+exportMethod+`', pth)
+			end)
+		end, { nargs = 1, complete = 'file' })
+	`, nil, n.Engine.ChannelID())
+}
+
+// Writes the screen of n to the file at pth
+func exportScreen(n *nvim.NeoVim, pth string) error {
+	f, err := os.Create(pth)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(pth)) {
+	case ".png":
+		err = n.ExportPNG(f)
+	case ".html", ".htm":
+		err = n.ExportHTML(f)
+	default:
+		err = n.ExportANSI(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Shows msg in nvim with the given vim.log.levels name
+func notify(n *nvim.NeoVim, msg string, level string) {
+	err := n.Engine.ExecLua(`
+		local msg, level = ...
+		vim.notify(msg, vim.log.levels[level])
+	`, nil, msg, level)
+	if err != nil {
+		fmt.Println("Error notifying nvim: ", err)
+	}
+}
+
+// Records the session as asciicast to the file at pth until we exit. Frames
+// are written as they come, so the file is left open for the OS to close.
+func recordSession(n *nvim.NeoVim, pth string) error {
+	f, err := os.Create(pth)
+	if err != nil {
+		return err
+	}
+
+	n.StartRecording(f)
+	return nil
+}
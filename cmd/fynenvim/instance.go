@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"fyne.io/fyne/v2"
+	"github.com/neovim/go-client/nvim"
+)
+
+// The notification sent by nvim once the user is done with a buffer someone
+// waits for
+const bufClosedMethod = "fynenvim_buf_closed"
+
+// Sent by a second fynenvim to the running instance
+type openRequest struct {
+	Cwd        string   `json:"cwd"`
+	Positional []string `json:"positional"`
+	Wait       bool     `json:"wait"`
+}
+
+// The answer of the running instance, sent once the files were opened or, when
+// waiting, all of them got closed again
+type openResponse struct {
+	Code  int    `json:"code"`
+	Error string `json:"error,omitempty"`
+}
+
+// Returns the well-known socket the first instance of the user listens on. It
+// is kept in a directory only the user may enter, XDG_RUNTIME_DIR or one of our
+// own in the temporary directory, so nobody else can connect to it or put a
+// socket of their own in its place.
+func socketPath() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "fynenvim-"+strconv.Itoa(os.Getuid()))
+		err := os.Mkdir(dir, 0o700)
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+
+	err := checkPrivateDir(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fynenvim.sock"), nil
+}
+
+// Checks that only we may enter dir, i.e. that it is a directory rather than a
+// link to one, owned by us and closed to everyone else
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if uid, ok := fileOwner(info); !ok || uid != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the user", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s is accessible by other users", dir)
+	}
+	return nil
+}
+
+// Returns the user id of the process on the other end of a unix socket
+func peerUID(conn net.Conn) (int, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, errors.New("not a unix socket")
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var uid int
+	var uidErr error
+	err = raw.Control(func(fd uintptr) {
+		uid, uidErr = socketPeerUID(int(fd))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uid, uidErr
+}
+
+// Hands the files over to an already running instance. Returns false if there
+// is none, otherwise the exit code to use.
+func forward(cfg *config) (int, bool) {
+	pth, err := socketPath()
+	if err != nil {
+		return 0, false
+	}
+	conn, err := net.Dial("unix", pth)
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+
+	req := openRequest{Cwd: cfg.cwd, Positional: cfg.positional, Wait: cfg.wait}
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return 0, false
+	}
+
+	var resp openResponse
+	err = json.NewDecoder(conn).Decode(&resp)
+	if err != nil {
+		fmt.Println("Error waiting for running instance: ", err)
+		return 1, true
+	}
+	if resp.Error != "" {
+		fmt.Println("Error opening files in running instance: ", resp.Error)
+	}
+
+	return resp.Code, true
+}
+
+// Accepts requests of later fynenvim invocations and opens their files in
+// new tabs of our nvim
+type instanceServer struct {
+	engine   *nvim.Nvim
+	window   fyne.Window
+	listener net.Listener
+
+	mu      sync.Mutex
+	nextID  int
+	waiting map[int]*waiter
+	exited  bool
+	code    int
+}
+
+// A client waiting for its buffers to be closed
+type waiter struct {
+	remaining int
+	done      chan int
+}
+
+// Starts listening on the well-known socket. A socket left behind by a crashed
+// instance is replaced, while one another instance started at the same time
+// already listens on is left to it.
+func listenForInstances(engine *nvim.Nvim, w fyne.Window) (*instanceServer, error) {
+	pth, err := socketPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("unix", pth)
+	if err == nil {
+		conn.Close()
+		return nil, errors.New("another instance is already listening on " + pth)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		os.Remove(pth)
+	}
+
+	l, err := net.Listen("unix", pth)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(pth, 0o600)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	s := &instanceServer{
+		engine:   engine,
+		window:   w,
+		listener: l,
+		waiting:  make(map[int]*waiter),
+	}
+	err = engine.RegisterHandler(bufClosedMethod, s.bufClosed)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	go s.serve()
+	return s, nil
+}
+
+// Stops accepting requests and releases everyone waiting with nvim's exit code
+func (s *instanceServer) Close(code int) {
+	s.listener.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exited = true
+	s.code = code
+	for id, w := range s.waiting {
+		w.done <- code
+		delete(s.waiting, id)
+	}
+}
+
+func (s *instanceServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		// +cmd arguments are run as they are, so only the user may send them
+		if uid, err := peerUID(conn); err != nil || uid != os.Getuid() {
+			conn.Close()
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+// Handles a single request
+func (s *instanceServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req openRequest
+	err := json.NewDecoder(conn).Decode(&req)
+	if err != nil {
+		return
+	}
+
+	resp := openResponse{}
+	code, err := s.open(req)
+	resp.Code = code
+	if err != nil {
+		resp.Code = 1
+		resp.Error = err.Error()
+	}
+
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// Opens the requested files and, if asked to, blocks until all of them got
+// closed or nvim exited
+func (s *instanceServer) open(req openRequest) (int, error) {
+	bufs, err := openInRunning(s.engine, req.Cwd, req.Positional, "tabedit")
+	if err != nil {
+		return 1, err
+	}
+	s.window.RequestFocus()
+
+	if !req.Wait || len(bufs) == 0 {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	if s.exited {
+		s.mu.Unlock()
+		return s.code, nil
+	}
+	id := s.nextID
+	s.nextID++
+	w := &waiter{remaining: len(bufs), done: make(chan int, 1)}
+	s.waiting[id] = w
+	s.mu.Unlock()
+
+	// like nvr --remote-wait, closing the window deletes the buffers opened
+	// for the request, so we get notified as soon as the user is done with
+	// them. Buffers the user already had keep their options, we are done with
+	// them once they are no longer shown.
+	handles := make([]nvim.Buffer, len(bufs))
+	created := make([]bool, len(bufs))
+	for i, buf := range bufs {
+		handles[i], created[i] = buf.Buffer, buf.Created
+	}
+	err = s.engine.ExecLua(`
+		local chan, id, bufs, created = ...
+		for i, buf in ipairs(bufs) do
+			local events = { 'BufDelete' }
+			if created[i] then
+				vim.bo[buf].bufhidden = 'delete'
+			else
+				events = { 'BufHidden', 'BufUnload', 'BufDelete' }
+			end
+			local notified = false
+			vim.api.nvim_create_autocmd(events, {
+				buffer = buf,
+				callback = function()
+					if not notified then
+						notified = true
+						vim.rpcnotify(chan, '`+bufClosedMethod+`', id)
+					end
+					return true
+				end,
+			})
+		end
+	`, nil, s.engine.ChannelID(), id, handles, created)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.waiting, id)
+		s.mu.Unlock()
+		return 1, err
+	}
+
+	return <-w.done, nil
+}
+
+// Handles the notification nvim sends once the user is done with a buffer
+func (s *instanceServer) bufClosed(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.waiting[id]
+	if !ok {
+		return
+	}
+
+	w.remaining--
+	if w.remaining == 0 {
+		w.done <- 0
+		delete(s.waiting, id)
+	}
+}
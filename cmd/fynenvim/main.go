@@ -2,22 +2,119 @@ package main
 
 import (
 	"fmt"
+	"os"
 
-	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	nvim "github.com/yesoer/fyne-nvim"
 )
 
 func main() {
-	a := app.New()
+	cfg := mustParseArgs()
+	if cfg.forwardable() {
+		if code, ok := forward(cfg); ok {
+			os.Exit(code)
+		}
+	}
+	if !cfg.wait && detach() {
+		return
+	}
+
+	a := app.NewWithID("io.github.yesoer.fynenvim")
+	restoreWindowState(cfg, a.Preferences())
+	applyFontSize(cfg, a)
+
 	w := a.NewWindow("Fyne NeoVim Example")
-	w.Resize(fyne.NewSize(900, 600))
+	w.Resize(cfg.geometry)
+
+	if cfg.replay != "" {
+		showReplay(a, w, cfg)
+		return
+	}
+
+	// nvim may exit before the window is even shown, e.g. because of an error
+	// in its config, so only its exit code is passed on until we are set up
+	exited := make(chan int, 1)
+	opts := cfg.options()
+	opts.OnExit = func(code int) { exited <- code }
+	nvim := nvim.NewWithOptions(cfg.cwd, opts)
+	if nvim.Engine == nil {
+		os.Exit(1)
+	}
+
+	if cfg.recordRedraws != "" {
+		err := recordRedraws(nvim, cfg.recordRedraws)
+		if err != nil {
+			fmt.Println("Error recording redraws: ", err)
+		}
+	}
+
+	if cfg.record != "" {
+		err := recordSession(nvim, cfg.record)
+		if err != nil {
+			fmt.Println("Error recording session: ", err)
+		}
+	}
+
+	err := setupExport(nvim)
+	if err != nil {
+		fmt.Println("Error adding export command: ", err)
+	}
 
-	nvim := nvim.New("./")
-	nvim.Resize(fyne.NewSize(900, 600))
+	// become the instance later invocations hand their files to, unless we are
+	// waited for, e.g. by git, which would then wait for their files as well
+	var instances *instanceServer
+	if cfg.forwardable() && !cfg.wait {
+		instances, err = listenForInstances(nvim.Engine, w)
+		if err != nil {
+			fmt.Println("Error listening for other instances: ", err)
+		}
+	}
+
+	// the window lives as long as nvim does, e.g. :cq makes us exit with 1
+	exitCode := 0
+	go func() {
+		exitCode = <-exited
+		saveWindowState(cfg, a.Preferences(), w)
+		if instances != nil {
+			instances.Close(exitCode)
+		}
+		a.Quit()
+	}()
+
+	// let nvim decide whether to quit, as there may be unsaved changes. An
+	// attached server is left running.
+	if cfg.server == "" {
+		w.SetCloseIntercept(func() {
+			go nvim.Engine.Command("confirm qall")
+		})
+	}
+
+	nvim.Resize(cfg.geometry)
 	w.SetContent(nvim)
 	w.Canvas().Focus(nvim)
 
+	if cfg.session && cfg.server == "" {
+		err := setupSession(nvim.Engine, cfg)
+		if err != nil {
+			fmt.Println("Error setting up session: ", err)
+		}
+	}
+
+	// a running server never sees our arguments, so open everything via RPC
+	if cfg.server != "" {
+		_, err := openInRunning(nvim.Engine, cfg.cwd, cfg.positional, "edit")
+		if err != nil {
+			fmt.Println("Error opening files: ", err)
+		}
+	}
+
+	if cfg.fullscreen {
+		w.SetFullScreen(true)
+	} else if cfg.maximized {
+		maximize(a, w)
+	}
+
 	fmt.Println("show and run")
 	w.ShowAndRun()
+	os.Exit(exitCode)
 }
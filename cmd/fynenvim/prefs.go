@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"github.com/neovim/go-client/nvim"
+)
+
+// Keys of the values kept in the fyne preferences
+const (
+	prefWidth      = "window.width"
+	prefHeight     = "window.height"
+	prefFullscreen = "window.fullscreen"
+	prefFontSize   = "font.size"
+)
+
+// The window size used if neither given nor remembered
+var defaultGeometry = fyne.NewSize(900, 600)
+
+// Applies the remembered window state and font size to everything which was not
+// given on the command line. Fyne does not expose the window position, so it
+// can't be restored and is left to the window manager.
+func restoreWindowState(cfg *config, prefs fyne.Preferences) {
+	if !cfg.set["geometry"] {
+		cfg.geometry = fyne.NewSize(
+			float32(prefs.FloatWithFallback(prefWidth, float64(defaultGeometry.Width))),
+			float32(prefs.FloatWithFallback(prefHeight, float64(defaultGeometry.Height))))
+	}
+
+	// asking for a maximized window rules out the remembered fullscreen
+	if !cfg.set["fullscreen"] && !cfg.maximized {
+		cfg.fullscreen = prefs.Bool(prefFullscreen)
+	}
+
+	if !cfg.set["font-size"] {
+		cfg.fontSize = prefs.Float(prefFontSize)
+	}
+}
+
+// Remembers the current window state and font size for the next start
+func saveWindowState(cfg *config, prefs fyne.Preferences, w fyne.Window) {
+	fullscreen := w.FullScreen()
+	prefs.SetBool(prefFullscreen, fullscreen)
+
+	// the fullscreen size is not the one to restore to
+	if !fullscreen {
+		size := w.Canvas().Size()
+		prefs.SetFloat(prefWidth, float64(size.Width))
+		prefs.SetFloat(prefHeight, float64(size.Height))
+	}
+
+	if cfg.fontSize > 0 {
+		prefs.SetFloat(prefFontSize, cfg.fontSize)
+	}
+}
+
+// Overrides the text size of a theme
+type sizedTheme struct {
+	fyne.Theme
+	textSize float32
+}
+
+// Size implements fyne.Theme
+func (t *sizedTheme) Size(n fyne.ThemeSizeName) float32 {
+	if n == theme.SizeNameText {
+		return t.textSize
+	}
+
+	return t.Theme.Size(n)
+}
+
+// Applies the configured font size, if any
+func applyFontSize(cfg *config, a fyne.App) {
+	if cfg.fontSize <= 0 {
+		return
+	}
+
+	a.Settings().SetTheme(&sizedTheme{theme.DefaultTheme(), float32(cfg.fontSize)})
+}
+
+// Returns the session file for a working directory. Sessions are stored in the
+// user config dir, named by a hash of the directory.
+func sessionPath(cwd string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(cwd))
+	name := filepath.Base(cwd) + "-" + hex.EncodeToString(sum[:8]) + ".vim"
+	return filepath.Join(dir, "fynenvim", "sessions", name), nil
+}
+
+// Restores the session of the working directory unless files were given, and
+// makes nvim save it again right before it exits
+func setupSession(v *nvim.Nvim, cfg *config) error {
+	pth, err := sessionPath(cfg.cwd)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(pth), 0o755)
+	if err != nil {
+		return err
+	}
+
+	var escaped string
+	err = v.Call("fnameescape", &escaped, pth)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.positional) == 0 {
+		if _, err := os.Stat(pth); err == nil {
+			err = v.Command("silent! source " + escaped)
+			if err != nil {
+				return fmt.Errorf("restoring session: %w", err)
+			}
+		}
+	}
+
+	// in a group, so sourcing the session again doesn't add it once more
+	_, err = v.Exec(`
+		augroup fynenvim_session
+		autocmd!
+		autocmd VimLeavePre * mksession! `+escaped+`
+		augroup END
+	`, map[string]interface{}{})
+	return err
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// A buffer of a file opened in the running nvim
+type openedBuffer struct {
+	Buffer  nvim.Buffer
+	Created bool // false if the file already had a buffer
+}
+
+// Opens files and executes +cmd arguments in an already running nvim.
+// Relative paths are resolved against cwd since the running nvim may have a
+// different working directory. The first file is opened using openCmd (e.g.
+// "edit" or "tabedit"), any further files get their own tab. Returns the
+// buffers of the opened files.
+func openInRunning(v *nvim.Nvim, cwd string, positional []string, openCmd string) ([]openedBuffer, error) {
+	var cmds []string
+	var bufs []openedBuffer
+	for _, arg := range positional {
+		if strings.HasPrefix(arg, "+") {
+			cmds = append(cmds, arg[1:])
+			continue
+		}
+
+		pth := arg
+		if !filepath.IsAbs(pth) {
+			pth = filepath.Join(cwd, pth)
+		}
+
+		var escaped string
+		err := v.Call("fnameescape", &escaped, pth)
+		if err != nil {
+			return nil, err
+		}
+
+		var existed int
+		err = v.Call("bufexists", &existed, pth)
+		if err != nil {
+			return nil, err
+		}
+
+		err = v.Command(openCmd + " " + escaped)
+		if err != nil {
+			return nil, err
+		}
+		openCmd = "tabedit"
+
+		buf, err := v.CurrentBuffer()
+		if err != nil {
+			return nil, err
+		}
+		bufs = append(bufs, openedBuffer{Buffer: buf, Created: existed == 0})
+	}
+
+	// like nvim, commands run after the files have been loaded
+	for _, cmd := range cmds {
+		if cmd == "" {
+			// a bare "+" jumps to the last line
+			cmd = "$"
+		}
+		err := v.Command(cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bufs, nil
+}
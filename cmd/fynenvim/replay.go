@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	nvim "github.com/yesoer/fyne-nvim"
+)
+
+// Shows the redraws recorded with --record-redraws in real time
+func showReplay(a fyne.App, w fyne.Window, cfg *config) {
+	f, err := os.Open(cfg.replay)
+	if err != nil {
+		fmt.Println("Error opening recording: ", err)
+		os.Exit(1)
+	}
+
+	n := nvim.NewUnattached()
+	n.SetOptions(cfg.options())
+	w.SetContent(n)
+	go func() {
+		defer f.Close()
+		err := n.ReplayRedraws(f, true)
+		if err != nil {
+			fmt.Println("Error replaying redraws: ", err)
+		}
+	}()
+
+	w.ShowAndRun()
+}
+
+// Records the redraws of n to the file at pth until we exit. Every batch is
+// written right away, so the file is left open for the OS to close.
+func recordRedraws(n *nvim.NeoVim, pth string) error {
+	f, err := os.Create(pth)
+	if err != nil {
+		return err
+	}
+
+	n.RecordRedraws(f)
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// Fyne does not expose window maximization or the size of the screen, so as an
+// approximation the window starts in fullscreen to find out how large the
+// screen is. Once it is shown it leaves the fullscreen mode again, keeping
+// that size for the window manager to fit into its work area.
+func maximize(a fyne.App, w fyne.Window) {
+	var once sync.Once
+	w.SetFullScreen(true)
+	a.Lifecycle().SetOnEnteredForeground(func() {
+		once.Do(func() {
+			size := w.Canvas().Size()
+			w.SetFullScreen(false)
+			w.Resize(size)
+		})
+	})
+}
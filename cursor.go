@@ -0,0 +1,312 @@
+package nvim
+
+import (
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+)
+
+// How many rectangles follow the cursor while it moves, if CursorTrail is set
+const cursorTrailLength = 4
+
+// UnfocusedCursor is how the cursor looks while the widget doesn't have the
+// focus, which tells apart the editor receiving the input from others
+type UnfocusedCursor int
+
+const (
+	CursorHollow    UnfocusedCursor = iota // outlines the cell, the default
+	CursorDimmed                           // the cursor of the mode, half transparent
+	CursorHidden                           // not drawn at all
+	CursorUnchanged                        // the same as with the focus
+)
+
+// Draws the cursor above the cells, in the shape and colors of the current
+// mode. A block cursor covers the text of its cell, so it draws the text again
+// in the colors of the cursor. If CursorAnimation is set, the cursor slides to
+// the cell it moved to.
+type cursorOverlay struct {
+	rect   *canvas.Rectangle
+	text   *canvas.Text
+	shaped *shapedText         // instead of text, like the cell is drawn
+	trail  []*canvas.Rectangle // closest to the cursor first, if CursorTrail is set
+
+	from, to fyne.Position // where it moved between
+	pos      fyne.Position // where it is drawn right now
+	placed   bool          // whether it was drawn in a cell yet
+	anim     *fyne.Animation
+}
+
+func newCursorOverlay(trail bool) *cursorOverlay {
+	c := &cursorOverlay{
+		rect:   canvas.NewRectangle(color.Transparent),
+		text:   canvas.NewText("", color.Transparent),
+		shaped: newShapedText(),
+	}
+	c.text.Hide()
+	c.shaped.Hide()
+	if trail {
+		for i := 0; i < cursorTrailLength; i++ {
+			rect := canvas.NewRectangle(color.Transparent)
+			rect.Hide()
+			c.trail = append(c.trail, rect)
+		}
+	}
+
+	return c
+}
+
+// Returns the objects drawing the cursor, bottom to top
+func (c *cursorOverlay) objects() []fyne.CanvasObject {
+	objects := make([]fyne.CanvasObject, 0, len(c.trail)+2)
+	for i := len(c.trail) - 1; i >= 0; i-- {
+		objects = append(objects, c.trail[i])
+	}
+	return append(objects, c.rect, c.text, c.shaped.Raster)
+}
+
+// Returns the mode info of the current mode, a block if there is none
+func (n *NeoVim) currentModeInfo() modeInfo {
+	if n.modeIdx >= 0 && n.modeIdx < len(n.modeInfo) {
+		return n.modeInfo[n.modeIdx]
+	}
+	return modeInfo{CursorShape: "block", CellPercentage: 100}
+}
+
+// Returns the colors of the cursor and of the text it covers. Without a
+// highlight of its own the cursor inverts the colors of its cell.
+func (n *NeoVim) cursorColors(info modeInfo, cell gridCell) (bg, fg color.Color) {
+	s := n.cellStyle(cell)
+	bg, fg = s.fg, s.bg
+
+	if hl, ok := n.hl[info.AttrID]; ok && info.AttrID > 0 {
+		if hl.Bg != RGBA_SENTINEL {
+			bg = hl.Bg
+		}
+		if hl.Fg != RGBA_SENTINEL {
+			fg = hl.Fg
+		}
+	}
+	return bg, fg
+}
+
+// Returns where within its cell the cursor is drawn and its size, for a cell
+// of the given width in cells. Hollow cursors are always drawn as a block.
+func (r *render) cursorRect(info modeInfo, width int, hollow bool) (fyne.Position, fyne.Size) {
+	cell := fyne.NewSize(float32(width)*r.metrics.Width, r.metrics.Height)
+
+	percentage := float32(info.CellPercentage)
+	if percentage <= 0 || percentage > 100 {
+		percentage = 100
+	}
+	// never thinner than a pixel
+	thickness := func(size float32) float32 {
+		thickness := size * percentage / 100
+		if pixel := 1 / r.scale; thickness < pixel {
+			thickness = pixel
+		}
+		return thickness
+	}
+
+	switch {
+	case hollow:
+		return fyne.NewPos(0, 0), cell
+	case info.CursorShape == "vertical":
+		return fyne.NewPos(0, 0), fyne.NewSize(thickness(r.metrics.Width), cell.Height)
+	case info.CursorShape == "horizontal":
+		height := thickness(cell.Height)
+		return fyne.NewPos(0, cell.Height-height), fyne.NewSize(cell.Width, height)
+	}
+	return fyne.NewPos(0, 0), cell
+}
+
+// Updates the cursor to look like the current mode requires and moves it to
+// its cell, returning the animation to start if it slides there
+func (r *render) updateCursor() *fyne.Animation {
+	c := r.cursor
+
+	look := CursorUnchanged
+	if !r.focused {
+		look = r.UnfocusedCursor
+	}
+
+	visible := r.cursorRow >= 0 && r.cursorRow < len(r.cells) &&
+		r.cursorCol >= 0 && r.cursorCol < len(r.cells[r.cursorRow])
+	if !visible || look == CursorHidden {
+		if c.rect.Visible() {
+			c.rect.Hide()
+			c.text.Hide()
+			c.shaped.Hide()
+			r.changed = append(r.changed, c.rect, c.text, c.shaped.Raster)
+		}
+		return nil
+	}
+
+	row := r.cells[r.cursorRow]
+	cell := row[r.cursorCol]
+	width := 1
+	if r.cursorCol+1 < len(row) && row[r.cursorCol+1].text == "" {
+		width = 2
+	}
+
+	info := r.currentModeInfo()
+	offset, size := r.cursorRect(info, width, look == CursorHollow)
+	bg, fg := r.cursorColors(info, cell)
+	c.style(r, cell, bg, fg, size, look)
+
+	to := r.origin.AddXY(float32(r.cursorCol)*r.metrics.Width, float32(r.cursorRow)*r.metrics.Height).
+		Add(offset)
+	if c.placed && to == c.to {
+		return nil
+	}
+
+	// the first time there is nothing to slide from
+	if !c.placed || r.CursorAnimation <= 0 {
+		c.placed = true
+		c.from, c.to = to, to
+		c.move(r, 1)
+		return nil
+	}
+
+	if c.anim != nil {
+		c.anim.Stop()
+	}
+	c.from, c.to = c.pos, to
+
+	var anim *fyne.Animation
+	anim = fyne.NewAnimation(r.CursorAnimation, func(progress float32) {
+		r.tickCursor(c, anim, progress)
+	})
+	anim.Curve = r.CursorEasing
+	if anim.Curve == nil {
+		anim.Curve = fyne.AnimationEaseOut
+	}
+	c.anim = anim
+
+	c.move(r, 0)
+	return c.anim
+}
+
+// Sets the colors and size of the cursor. A hollow cursor is only outlined
+// and a dimmed one lets the text of its cell shine through.
+func (c *cursorOverlay) style(r *render, cell gridCell, bg, fg color.Color, size fyne.Size, look UnfocusedCursor) {
+	fill, stroke := bg, color.Color(color.Transparent)
+	switch look {
+	case CursorHollow:
+		fill, stroke = color.Transparent, bg
+	case CursorDimmed:
+		fill = withAlpha(bg, 0.5)
+	}
+	if !c.rect.Visible() || c.rect.FillColor != fill || c.rect.StrokeColor != stroke ||
+		c.rect.Size() != size {
+		c.rect.Show()
+		c.rect.FillColor = fill
+		c.rect.StrokeColor = stroke
+		c.rect.StrokeWidth = 1
+		c.rect.Resize(size)
+		r.changed = append(r.changed, c.rect)
+	}
+
+	for i, rect := range c.trail {
+		// fading out towards the end of the trail
+		faded := withAlpha(bg, float32(cursorTrailLength-i)/(2*cursorTrailLength+2))
+		if rect.FillColor != faded || rect.Size() != size {
+			rect.FillColor = faded
+			rect.Resize(size)
+			r.changed = append(r.changed, rect)
+		}
+	}
+
+	// only an opaque block covers the text
+	text := cell.text
+	if look == CursorHollow || look == CursorDimmed ||
+		size.Width < r.metrics.Width || size.Height < r.metrics.Height {
+		text = ""
+	}
+	text = strings.TrimRight(text, " ")
+	style := r.cellStyle(cell)
+	textStyle := textStyleOf(style)
+
+	// drawn like the cell, shaped if it is
+	var fallback fyne.Resource
+	if text != "" && !batchable(text) {
+		fallback = r.fallbackFont(text, textStyle)
+	}
+	if text != "" && (fallback != nil || r.shaping && batchable(text)) {
+		if c.shaped.set(r, text, fg, textStyle, fallback, size) || !c.shaped.Visible() {
+			c.shaped.Show()
+			r.changed = append(r.changed, c.shaped.Raster)
+		}
+		text = ""
+	} else if c.shaped.Visible() {
+		c.shaped.Hide()
+		r.changed = append(r.changed, c.shaped.Raster)
+	}
+
+	if c.text.Text != text || c.text.Color != fg || c.text.TextStyle != textStyle ||
+		c.text.TextSize != r.textSize || c.text.Size() != size || c.text.Visible() != (text != "") {
+		c.text.Text = text
+		c.text.Color = fg
+		c.text.TextStyle = textStyle
+		c.text.TextSize = r.textSize
+		c.text.Resize(size)
+		if text != "" {
+			c.text.Show()
+		} else {
+			c.text.Hide()
+		}
+		r.changed = append(r.changed, c.text)
+	}
+}
+
+// Returns the color with its alpha multiplied by alpha
+func withAlpha(c color.Color, alpha float32) color.Color {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	nrgba.A = uint8(float32(nrgba.A) * alpha)
+	return nrgba
+}
+
+// Slides the cursor further, called for every frame of the animation
+func (r *render) tickCursor(c *cursorOverlay, anim *fyne.Animation, progress float32) {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
+	// replaced by another move meanwhile
+	if c.anim != anim {
+		return
+	}
+
+	c.move(r, progress)
+	r.refreshChanged()
+}
+
+// Moves the cursor and its trail to where it is at the given progress of the
+// animation
+func (c *cursorOverlay) move(r *render, progress float32) {
+	at := func(progress float32) fyne.Position {
+		if progress < 0 {
+			progress = 0
+		}
+		return fyne.NewPos(c.from.X+(c.to.X-c.from.X)*progress,
+			c.from.Y+(c.to.Y-c.from.Y)*progress)
+	}
+
+	c.pos = at(progress)
+	c.rect.Move(c.pos)
+	c.text.Move(c.pos)
+	c.shaped.Move(c.pos)
+	r.changed = append(r.changed, c.rect, c.text, c.shaped.Raster)
+
+	// the trail lags behind and disappears once the cursor arrived
+	trail := progress < 1 && c.from != c.to
+	for i, rect := range c.trail {
+		if trail {
+			rect.Move(at(progress - float32(i+1)*0.08))
+			rect.Show()
+		} else {
+			rect.Hide()
+		}
+		r.changed = append(r.changed, rect)
+	}
+}
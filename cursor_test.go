@@ -0,0 +1,61 @@
+package nvim_test
+
+import (
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+func TestCursorAnimation(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.CursorAnimation = time.Second
+	n.CursorEasing = fyne.AnimationLinear
+	n.CursorTrail = true
+	anims := nvimtest.HoldAnimations()
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.GridResize(10, 4),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("text", 0)...),
+		nvimtest.GridCursorGoto(0, 0),
+		nvimtest.Flush(),
+	)
+	fyne.CurrentApp().Driver().CanvasForObject(n).Focus(n)
+	// the cursor is drawn last, above its trail
+	_, rects := drawnObjects(n)
+	cell := cellSize(n)
+	w, h := cell.Width, cell.Height
+	assert.Equal(t, fyne.NewPos(0, 0), rects[len(rects)-1].Position())
+	assert.Zero(t, anims.Running())
+
+	peer.Redraw(
+		nvimtest.GridCursorGoto(2, 6),
+		nvimtest.Flush(),
+	)
+	assert.Equal(t, 1, anims.Running())
+	_, moving := drawnObjects(n)
+	assert.Equal(t, fyne.NewPos(0, 0), moving[len(moving)-1].Position())
+
+	// halfway the trail follows behind the cursor
+	anims.Tick(0.5)
+	_, moving = drawnObjects(n)
+	assert.Len(t, moving, len(rects)+4)
+	assert.Equal(t, fyne.NewPos(3*w, h), moving[len(moving)-1].Position())
+	for _, rect := range moving[len(moving)-5 : len(moving)-1] {
+		assert.Less(t, rect.Position().X, 3*w)
+	}
+
+	// once there the trail is hidden again
+	anims.Tick(1)
+	_, after := drawnObjects(n)
+	assert.Len(t, after, len(rects))
+	assert.Equal(t, fyne.NewPos(6*w, 2*h), after[len(after)-1].Position())
+
+	// the cells are left as they are
+	c, _ := n.CellAt(2, 6)
+	assert.Equal(t, white, c.Fg)
+	assert.Equal(t, black, c.Bg)
+}
@@ -0,0 +1,265 @@
+package nvim
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// DebugOverlayShortcut toggles the debug overlay when typed into the widget.
+// It is not sent to nvim.
+var DebugOverlayShortcut = &desktop.CustomShortcut{
+	KeyName:  fyne.KeyF12,
+	Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift,
+}
+
+// How many event types the overlay lists, the most frequent ones first
+const debugEventTypes = 8
+
+// Statistics on redraws to diagnose performance problems, collected all the
+// time as it is cheap compared to the redraw itself
+type debugStats struct {
+	mu sync.Mutex
+
+	events  map[string]int // calls per event type since the start
+	current debugWindow
+	last    debugWindow // the window before current, to calculate rates
+	grid    debugGrid   // as of the last redraw batch
+
+	overlay *debugOverlay // set while the overlay is shown
+}
+
+// Counts what happened since a point in time
+type debugWindow struct {
+	start       time.Time
+	batches     int
+	handling    time.Duration // time spent handling redraw batches
+	maxHandling time.Duration
+	refreshes   int
+	refreshing  time.Duration // time spent in render.Refresh
+}
+
+// The state of the grid reported by the overlay. It is owned by the goroutine
+// handling redraws, so that one takes a copy after every batch.
+type debugGrid struct {
+	rows, cols  int
+	mode, shape string
+}
+
+// Adds a handled redraw batch and the grid it left to the statistics
+func (s *debugStats) addBatch(events [][]interface{}, took time.Duration, grid debugGrid) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.grid = grid
+
+	if s.events == nil {
+		s.events = make(map[string]int)
+	}
+	for _, e := range events {
+		if name, ok := e[0].(string); ok {
+			s.events[name] += len(e) - 1
+		}
+	}
+
+	s.current.batches++
+	s.current.handling += took
+	if took > s.current.maxHandling {
+		s.current.maxHandling = took
+	}
+}
+
+// Adds a refresh of the renderer to the statistics
+func (s *debugStats) addRefresh(took time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current.refreshes++
+	s.current.refreshing += took
+}
+
+// Starts a new window, rates are reported for the previous one
+func (s *debugStats) roll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last = s.current
+	s.current = debugWindow{start: time.Now()}
+}
+
+// Shows the statistics and diagnostics on top of the grid
+type debugOverlay struct {
+	bg    *canvas.Rectangle
+	label *widget.Label
+	stop  chan struct{} // closed to stop updating the overlay
+	done  chan struct{} // closed once it stopped
+}
+
+// Shows or hides the debug overlay, which reports redraw statistics, the grid
+// and cell sizes and the current mode. It is updated once per second.
+func (n *NeoVim) SetDebugOverlay(show bool) {
+	if !show {
+		n.stopDebugOverlay()
+		n.Refresh()
+		return
+	}
+
+	n.debug.mu.Lock()
+	if n.debug.overlay != nil {
+		n.debug.mu.Unlock()
+		return
+	}
+	o := &debugOverlay{
+		bg:    canvas.NewRectangle(color.NRGBA{0, 0, 0, 200}),
+		label: widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	n.debug.overlay = o
+	n.debug.mu.Unlock()
+
+	n.debug.roll()
+	o.update(n)
+	go n.updateDebugOverlay(o)
+	n.Refresh()
+}
+
+// Hides the debug overlay, if it is shown, and waits until it is no longer
+// updated
+func (n *NeoVim) stopDebugOverlay() {
+	n.debug.mu.Lock()
+	o := n.debug.overlay
+	n.debug.overlay = nil
+	n.debug.mu.Unlock()
+
+	if o != nil {
+		close(o.stop)
+		<-o.done
+	}
+}
+
+// Returns whether the debug overlay is shown
+func (n *NeoVim) DebugOverlayShown() bool {
+	return n.debugOverlay() != nil
+}
+
+func (n *NeoVim) debugOverlay() *debugOverlay {
+	n.debug.mu.Lock()
+	defer n.debug.mu.Unlock()
+
+	return n.debug.overlay
+}
+
+// Updates the overlay every second until it is hidden
+func (n *NeoVim) updateDebugOverlay(o *debugOverlay) {
+	defer close(o.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+			n.debug.roll()
+			o.update(n)
+		}
+	}
+}
+
+// Shows the current statistics
+func (o *debugOverlay) update(n *NeoVim) {
+	o.label.SetText(n.DebugInfo())
+	o.layout(n.Size())
+}
+
+// Places the overlay in the top right corner of a widget of the given size
+func (o *debugOverlay) layout(s fyne.Size) {
+	size := o.label.MinSize()
+	pos := fyne.NewPos(s.Width-size.Width, 0)
+	if pos.X < 0 {
+		pos.X = 0
+	}
+
+	o.bg.Move(pos)
+	o.bg.Resize(size)
+	o.label.Move(pos)
+	o.label.Resize(size)
+}
+
+// DebugInfo returns the text shown by the debug overlay, e.g. to log it. The
+// grid and mode are the ones left by the last redraw batch.
+func (n *NeoVim) DebugInfo() string {
+	var b strings.Builder
+
+	n.debug.mu.Lock()
+	w, elapsed := n.debug.last, n.debug.current.start.Sub(n.debug.last.start)
+	if w.start.IsZero() {
+		// there is no complete window yet
+		w, elapsed = n.debug.current, time.Since(n.debug.current.start)
+	}
+	perSecond := func(v float64) float64 {
+		if elapsed <= 0 {
+			return 0
+		}
+		return v / elapsed.Seconds()
+	}
+
+	fmt.Fprintf(&b, "redraws    %.0f/s  %s/s handling  max %s\n",
+		perSecond(float64(w.batches)),
+		time.Duration(perSecond(float64(w.handling))).Round(time.Microsecond),
+		w.maxHandling.Round(time.Microsecond))
+	fmt.Fprintf(&b, "refreshes  %.0f/s  %s/s\n",
+		perSecond(float64(w.refreshes)),
+		time.Duration(perSecond(float64(w.refreshing))).Round(time.Microsecond))
+
+	type eventCount struct {
+		name  string
+		count int
+	}
+	counts := make([]eventCount, 0, len(n.debug.events))
+	for name, count := range n.debug.events {
+		counts = append(counts, eventCount{name, count})
+	}
+	grid := n.debug.grid
+	n.debug.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].count != counts[j].count {
+			return counts[i].count > counts[j].count
+		}
+		return counts[i].name < counts[j].name
+	})
+	if len(counts) > debugEventTypes {
+		counts = counts[:debugEventTypes]
+	}
+	b.WriteString("events")
+	for i, c := range counts {
+		if i > 0 {
+			b.WriteString("      ")
+		}
+		fmt.Fprintf(&b, "     %-16s %d\n", c.name, c.count)
+	}
+	if len(counts) == 0 {
+		b.WriteString("     none\n")
+	}
+
+	cell := n.cellMetrics()
+	size := n.Size()
+	fitRows, fitCols := n.fitGrid(n.availableSize())
+	fmt.Fprintf(&b, "grid       %dx%d cells, widget %.0fx%.0f fits %dx%d\n", grid.cols, grid.rows,
+		size.Width, size.Height, fitCols, fitRows)
+	fmt.Fprintf(&b, "cell       %.2fx%.2f, ascent %.2f descent %.2f at scale %g\n",
+		cell.Width, cell.Height, cell.Ascent, cell.Descent, n.scale())
+	fmt.Fprintf(&b, "mode       %s (%s)", grid.mode, grid.shape)
+
+	return b.String()
+}
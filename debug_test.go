@@ -0,0 +1,62 @@
+package nvim_test
+
+import (
+	"fmt"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+func TestDebugOverlay(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	assert.False(t, n.DebugOverlayShown())
+
+	n.SetDebugOverlay(true)
+	assert.True(t, n.DebugOverlayShown())
+	n.SetDebugOverlay(true)
+	assert.True(t, n.DebugOverlayShown())
+
+	n.SetDebugOverlay(false)
+	assert.False(t, n.DebugOverlayShown())
+
+	// the chord toggles the overlay instead of being sent to nvim
+	n.TypedShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyF12,
+		Modifier: fyne.KeyModifierShift | fyne.KeyModifierControl})
+	assert.True(t, n.DebugOverlayShown())
+	n.TypedShortcut(nvim.DebugOverlayShortcut)
+	assert.False(t, n.DebugOverlayShown())
+	assert.Empty(t, inputs(peer))
+
+	// and stops being updated with the widget
+	n.SetDebugOverlay(true)
+	test.WidgetRenderer(n).Destroy()
+	assert.False(t, n.DebugOverlayShown())
+}
+
+func TestDebugInfo(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	drawScene(peer)
+
+	info := n.DebugInfo()
+	assert.Regexp(t, `redraws +\d+/s`, info)
+	assert.Regexp(t, `grid_line +3\n`, info)
+	assert.Regexp(t, `flush +1\n`, info)
+	assert.Contains(t, info, "grid       16x4 cells")
+	assert.Contains(t, info, "mode       insert (vertical)")
+}
+
+func TestDebugInfoPadding(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.Padding = 20
+	c := fyne.CurrentApp().Driver().CanvasForObject(n).(test.WindowlessCanvas)
+	c.Resize(fyne.NewSize(403, 211))
+
+	// what the grid was fitted to, within the padding
+	cols, rows := lastTryResize(t, peer)
+	assert.Contains(t, n.DebugInfo(), fmt.Sprintf("fits %dx%d\n", cols, rows))
+}
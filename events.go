@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"image/color"
 	"reflect"
-
-	"fyne.io/fyne/v2"
+	"strings"
 )
 
 // Handles events for the NeoVim instance
@@ -37,6 +36,13 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 		case "mode_info_set":
 			// Additional entries: cursor_style_enabled, mode_info
 
+			infos, _ := entries[1].([]interface{})
+			n.modeInfo = make([]modeInfo, 0, len(infos))
+			for _, info := range infos {
+				infoMap, _ := info.(map[string]interface{})
+				n.modeInfo = append(n.modeInfo, modeInfoFromMap(infoMap))
+			}
+
 		case "option_set":
 			// Additional entries: name, value
 
@@ -46,6 +52,9 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 		case "mode_change":
 			// Additional entries: mode, mode_idx
 
+			n.mode, _ = entries[0].(string)
+			n.modeIdx = n.eventInt(event[0], entries[1])
+
 		case "mouse_on":
 			// No additional entries
 
@@ -77,6 +86,7 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// No additional entries
 
 			n.Refresh()
+			n.recordFrame()
 
 		//-------------------------Grid Events (line-based)-------------------------
 
@@ -84,16 +94,17 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// The grid is resized to width and height cells.
 			// Additional entries: grid, width, height
 
-			colsCnt, _ := intOrUintToInt(entries[1])
-			rowsCnt, _ := intOrUintToInt(entries[2])
+			colsCnt := n.eventInt(event[0], entries[1])
+			rowsCnt := n.eventInt(event[0], entries[2])
 			n.ChangeVisualGridSize(rowsCnt, colsCnt)
 
-			cellSize := guessCellSize()
-			s := fyne.NewSize(float32(colsCnt*int(cellSize.Width)),
-				float32(rowsCnt*int(cellSize.Height)))
+			// The widget keeps the size it was given, so the part which is
+			// smaller than a cell is drawn in the default background instead
+			// of leaving a gutter of whatever is behind the widget.
+			s := n.cellMetrics().pixelSize(rowsCnt, colsCnt).
+				AddWidthHeight(2*n.Padding, 2*n.Padding)
 
-			n.BaseWidget.Resize(s) // must be included
-			n.content.Resize(s)
+			n.BaseWidget.Resize(s.Max(n.availableSize())) // must be included
 
 		case "default_colors_set":
 			// The RGB values will always be valid colors, by default. If no colors
@@ -107,10 +118,15 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// screen with changed background color itself.
 			// Additional entries: rgb_fg, rgb_bg, rgb_sp, cterm_fg, cterm_bg
 
-			defaultHL.Fg, _ = extractRGBA(entries[0])
-			defaultHL.Bg, _ = extractRGBA(entries[1])
-			defaultHL.Special, _ = extractRGBA(entries[2])
+			n.gridMu.Lock()
+			n.defaultHL.Fg, _ = extractRGBA(entries[0])
+			n.defaultHL.Bg, _ = extractRGBA(entries[1])
+			n.defaultHL.Special, _ = extractRGBA(entries[2])
+			n.gridMu.Unlock()
 			// cterm_fg, cterm_bg are ignored
+
+			// every style may fall back to the default colors
+			n.styles = make(map[int]*gridStyle)
 			n.Refresh()
 
 		case "hl_attr_define":
@@ -121,7 +137,7 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// semantic information.
 			// Additional entries: id, rgb_attr, cterm_attr, info
 
-			id, _ := intOrUintToInt(entries[0])
+			id := n.eventInt(event[0], entries[0])
 
 			rgbAttr := entries[1].(map[string]interface{})
 
@@ -130,8 +146,13 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 				Bg:      RGBA_SENTINEL,
 				Special: RGBA_SENTINEL,
 			}
-			setHLFromMap(rgbAttr, &newHL)
+			err := setHLFromMap(rgbAttr, &newHL)
+			if err != nil {
+				n.log().Warn("invalid highlight attribute", "event", event[0],
+					"id", id, "err", err)
+			}
 			n.hl[id] = newHL
+			delete(n.styles, id)
 
 			// Info is ignored since we don't need semantic information as of
 			// now
@@ -161,8 +182,8 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// first column of the next row.
 			// Additional entries: grid, row, col_start, cells, wrap
 
-			row, _ := intOrUintToInt(entries[1])
-			col, _ := intOrUintToInt(entries[2])
+			row := n.eventInt(event[0], entries[1])
+			col := n.eventInt(event[0], entries[2])
 			cells := entries[3].([]interface{})
 			// wrap := entries[4].(bool) // TODO : use wrap
 
@@ -179,7 +200,10 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// with it.
 			// Additional entries: grid
 
-			n.content = nil
+			n.gridMu.Lock()
+			n.cells = nil
+			n.dirty = nil
+			n.gridMu.Unlock()
 
 		case "grid_cursor_goto":
 			// Makes grid the current grid and row, column the cursor position on
@@ -187,11 +211,11 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// indicates the visible cursor position.
 			// Additional entries: grid, row, column
 
-			oldRow, oldCol := n.cursorRow, n.cursorCol
-			newRow, _ := entries[1].(int64)
-			newCol, _ := entries[2].(int64)
+			oldRow, oldCol := n.CursorPosition()
+			newRow := n.eventInt(event[0], entries[1])
+			newCol := n.eventInt(event[0], entries[2])
 
-			n.MoveGridCursor(oldRow, oldCol, int(newRow), int(newCol))
+			n.MoveGridCursor(oldRow, oldCol, newRow, newCol)
 
 		case "grid_scroll":
 			// Scroll a region of grid. This is semantically unrelated to editor
@@ -208,46 +232,82 @@ func (n *NeoVim) HandleNvimEvent(event []interface{}) {
 			// clear this area as part of handling the scroll event.
 			// Additional entries: grid, top, bot, left, right, rows, cols
 
-			top, _ := intOrUintToInt(entries[1])
-			bot, _ := intOrUintToInt(entries[2])
-			left, _ := intOrUintToInt(entries[3])
-			right, _ := intOrUintToInt(entries[4])
-			rows, _ := intOrUintToInt(entries[5])
+			top := n.eventInt(event[0], entries[1])
+			bot := n.eventInt(event[0], entries[2])
+			left := n.eventInt(event[0], entries[3])
+			right := n.eventInt(event[0], entries[4])
+			rows := n.eventInt(event[0], entries[5])
 
 			n.ScrollGrid(top, bot, left, right, rows)
 
 		default:
-			// Handle unknown entry type
-			fmt.Println("Unknown event type: ", event[0])
+			// Nvim may send events we have no use for, e.g. from extensions
+			// which aren't enabled
+			n.log().Debug("ignoring unknown event", "event", event[0])
 		}
 	}
 }
 
 // Expects a map which defines the attributes for highlighting etc. and a target
-// to write them to.
-func setHLFromMap(personMap map[string]interface{}, target *highlight) {
+// to write them to. Attributes of an unexpected type are skipped and reported
+// in the returned error.
+func setHLFromMap(personMap map[string]interface{}, target *highlight) error {
 	targetValue := reflect.ValueOf(target).Elem()
 
+	var invalid []string
 	for i := 0; i < targetValue.NumField(); i++ {
 		field := targetValue.Type().Field(i)
 		tag := field.Tag.Get("map")
 		if value, ok := personMap[tag]; ok {
+			converted := value
 			if field.Type == reflect.TypeOf(color.RGBA{}) {
-				value, _ = extractRGBA(value)
-				if !ok {
-					fmt.Println("Unknown type: ", value)
-					continue
-				}
+				converted, ok = extractRGBA(value)
+			} else {
+				ok = value != nil && reflect.TypeOf(value).AssignableTo(field.Type)
 			}
-			targetValue.Field(i).Set(reflect.ValueOf(value))
+			if !ok {
+				invalid = append(invalid, fmt.Sprintf("%s=%v (%T)", tag, value, value))
+				continue
+			}
+			targetValue.Field(i).Set(reflect.ValueOf(converted))
 		}
 	}
 
 	if target.Reverse {
 		target.Fg, target.Bg = target.Bg, target.Fg
 	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("unexpected type of %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// Expects a map as sent for each mode by mode_info_set, unset values default to
+// a block cursor
+func modeInfoFromMap(m map[string]interface{}) modeInfo {
+	info := modeInfo{CursorShape: "block", CellPercentage: 100}
+
+	if v, ok := m["name"].(string); ok {
+		info.Name = v
+	}
+	if v, ok := m["short_name"].(string); ok {
+		info.ShortName = v
+	}
+	if v, ok := m["cursor_shape"].(string); ok {
+		info.CursorShape = v
+	}
+	if v, ok := m["cell_percentage"]; ok {
+		info.CellPercentage, _ = intOrUintToInt(v)
+	}
+	if v, ok := m["attr_id"]; ok {
+		info.AttrID, _ = intOrUintToInt(v)
+	}
+
+	return info
 }
 
+// msgpack decodes positive numbers as int64 or uint64 depending on their size
 func intOrUintToInt(i interface{}) (int, bool) {
 	switch i.(type) {
 	case uint64:
@@ -255,11 +315,20 @@ func intOrUintToInt(i interface{}) (int, bool) {
 	case int64:
 		return int(i.(int64)), true
 	default:
-		fmt.Println("Unknown type: ", i)
 		return 0, false
 	}
 }
 
+// Converts a number sent as part of event, warning about anything else
+func (n *NeoVim) eventInt(event interface{}, i interface{}) int {
+	v, ok := intOrUintToInt(i)
+	if !ok {
+		n.log().Warn("expected a number", "event", event, "value", i,
+			"type", fmt.Sprintf("%T", i))
+	}
+	return v
+}
+
 // Expects a uint64 or int64 and returns its corresponding color.RGBA
 func extractRGBA(i interface{}) (color.RGBA, bool) {
 	n, ok := intOrUintToInt(i)
@@ -0,0 +1,227 @@
+package nvim_test
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	black = color.RGBA{0, 0, 0, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+// Asserts the text of a row starting at col
+func assertText(t *testing.T, n *nvim.NeoVim, row, col int, want string) {
+	t.Helper()
+
+	for i, r := range want {
+		c, ok := n.CellAt(row, col+i)
+		if assert.True(t, ok, "cell %d,%d outside of grid", row, col+i) {
+			assert.Equal(t, string(r), c.Text, "cell %d,%d", row, col+i)
+		}
+	}
+}
+
+func TestAttachUI(t *testing.T) {
+	_, peer := nvimtest.New(t)
+
+	args, ok := peer.LastCall("nvim_ui_attach")
+	assert.True(t, ok)
+	assert.EqualValues(t, nvim.MIN_COLS, args[0])
+	assert.EqualValues(t, nvim.MIN_ROWS, args[1])
+}
+
+func TestGridResize(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(nvimtest.GridResize(20, 5))
+	rows, cols := n.GridSize()
+	assert.Equal(t, 5, rows)
+	assert.Equal(t, 20, cols)
+
+	peer.Redraw(nvimtest.GridResize(30, 8))
+	rows, cols = n.GridSize()
+	assert.Equal(t, 8, rows)
+	assert.Equal(t, 30, cols)
+}
+
+func TestGridLine(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.GridResize(20, 5),
+		nvimtest.GridLine(1, 2,
+			nvimtest.Cell("h", 0),
+			nvimtest.Cell("i"),
+			nvimtest.Cell("-", 0, 3),
+			nvimtest.Cell("!")),
+		nvimtest.Flush(),
+	)
+
+	assertText(t, n, 1, 2, "hi---!")
+	c, _ := n.CellAt(1, 8)
+	assert.Equal(t, " ", c.Text)
+}
+
+func TestGridClear(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.GridResize(20, 5),
+		nvimtest.GridLine(0, 0, nvimtest.Cell("x", 0, 20)),
+		nvimtest.GridClear(),
+	)
+
+	assertText(t, n, 0, 0, "                    ")
+}
+
+func TestGridScroll(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	lines := []string{"a", "b", "c", "d", "e"}
+	events := [][]interface{}{nvimtest.GridResize(20, 5)}
+	for i, l := range lines {
+		events = append(events, nvimtest.GridLine(i, 0, nvimtest.Cell(l, 0)))
+	}
+	peer.Redraw(events...)
+
+	// move rows 1 to 3 up by one, as when scrolling down
+	peer.Redraw(nvimtest.GridScroll(0, 4, 0, 20, 1))
+	assertText(t, n, 0, 0, "b")
+	assertText(t, n, 1, 0, "c")
+	assertText(t, n, 2, 0, "d")
+	assertText(t, n, 4, 0, "e")
+}
+
+func TestGridScrollOutside(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.ScrollAnimation = time.Second
+	nvimtest.HoldAnimations()
+
+	lines := []string{"a", "b", "c"}
+	events := [][]interface{}{nvimtest.GridResize(10, 3)}
+	for i, l := range lines {
+		events = append(events, nvimtest.GridLine(i, 0, nvimtest.Cell(l, 0)))
+	}
+	peer.Redraw(events...)
+
+	// a region reaching past the grid is limited to it
+	peer.Redraw(nvimtest.GridScroll(-1, 5, 0, 20, 1), nvimtest.Flush())
+	assertText(t, n, 0, 0, "b")
+	assertText(t, n, 1, 0, "c")
+
+	// as are rows beyond the region's height
+	peer.Redraw(nvimtest.GridScroll(0, 3, 0, 10, -7), nvimtest.Flush())
+	assertText(t, n, 2, 0, "c")
+}
+
+func TestGridCursorGoto(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.GridResize(200, 5),
+		nvimtest.GridCursorGoto(2, 3),
+	)
+	row, col := n.CursorPosition()
+	assert.Equal(t, 2, row)
+	assert.Equal(t, 3, col)
+
+	// msgpack decodes larger numbers as unsigned
+	peer.Redraw(nvimtest.GridCursorGoto(4, 150))
+	row, col = n.CursorPosition()
+	assert.Equal(t, 4, row)
+	assert.Equal(t, 150, col)
+}
+
+func TestHighlight(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.GridResize(20, 5),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{
+			"foreground": red,
+			"background": green,
+		}),
+		nvimtest.HLAttrDefine(2, map[string]interface{}{
+			"foreground": red,
+			"background": green,
+			"reverse":    true,
+		}),
+		nvimtest.HLAttrDefine(3, map[string]interface{}{
+			"foreground": green,
+		}),
+		nvimtest.GridLine(0, 0,
+			nvimtest.Cell("a", 1),
+			nvimtest.Cell("b", 2),
+			nvimtest.Cell("c", 3),
+			nvimtest.Cell("d", 0)),
+	)
+
+	c, _ := n.CellAt(0, 0)
+	assert.Equal(t, red, c.Fg)
+	assert.Equal(t, green, c.Bg)
+
+	c, _ = n.CellAt(0, 1)
+	assert.Equal(t, green, c.Fg)
+	assert.Equal(t, red, c.Bg)
+
+	// unset colors fall back to the defaults
+	c, _ = n.CellAt(0, 2)
+	assert.Equal(t, green, c.Fg)
+	assert.Equal(t, black, c.Bg)
+
+	c, _ = n.CellAt(0, 3)
+	assert.Equal(t, white, c.Fg)
+	assert.Equal(t, black, c.Bg)
+}
+
+func TestHighlightRedefined(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.GridResize(20, 5),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"foreground": red}),
+		nvimtest.GridLine(0, 0, nvimtest.Cell("a", 1), nvimtest.Cell("b", 0)),
+	)
+
+	// e.g. after :colorscheme, nvim redefines the highlights and redraws
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(black, white, red),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"foreground": green}),
+		nvimtest.GridLine(0, 0, nvimtest.Cell("a", 1), nvimtest.Cell("b", 0)),
+	)
+
+	c, _ := n.CellAt(0, 0)
+	assert.Equal(t, green, c.Fg)
+	assert.Equal(t, white, c.Bg)
+
+	c, _ = n.CellAt(0, 1)
+	assert.Equal(t, black, c.Fg)
+	assert.Equal(t, white, c.Bg)
+}
+
+func TestDefaultColorsPerWidget(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	other, otherPeer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(black, white, red),
+		nvimtest.GridResize(20, 5),
+	)
+	otherPeer.Redraw(nvimtest.GridResize(20, 5))
+
+	// the colors of one nvim don't leak into the widget of another
+	c, _ := n.CellAt(0, 0)
+	assert.Equal(t, white, c.Bg)
+	c, _ = other.CellAt(0, 0)
+	assert.Equal(t, black, c.Bg)
+}
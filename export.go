@@ -0,0 +1,126 @@
+package nvim
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// ExportPNG writes what the widget currently shows on screen, including the
+// cursor, as PNG image. The widget has to be shown in a window.
+//
+// Note that with the GL driver this has to be called from another goroutine
+// than fyne's main one, e.g. from a handler of an nvim notification.
+func (n *NeoVim) ExportPNG(w io.Writer) error {
+	d := fyne.CurrentApp().Driver()
+	c := d.CanvasForObject(n)
+	if c == nil {
+		return errors.New("the widget is not shown in a window")
+	}
+
+	img := c.Capture()
+
+	// the capture is in pixels while positions are in canvas coordinates
+	pos := d.AbsolutePositionForObject(n)
+	x1, y1 := c.PixelCoordinateForPosition(pos)
+	x2, y2 := c.PixelCoordinateForPosition(pos.Add(n.Size()))
+	rect := image.Rect(x1, y1, x2, y2).Add(img.Bounds().Min).Intersect(img.Bounds())
+
+	cropped := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, rect.Min, draw.Src)
+
+	return png.Encode(w, cropped)
+}
+
+// ExportHTML writes the grid as a standalone HTML snippet, i.e. a pre element
+// with a span per run of equally styled cells
+func (n *NeoVim) ExportHTML(w io.Writer) error {
+	_, err := io.WriteString(w, n.Snapshot().HTML())
+	return err
+}
+
+// ExportANSI writes the grid as text with ANSI escape sequences, which can be
+// shown with e.g. cat in a terminal supporting 24-bit colors
+func (n *NeoVim) ExportANSI(w io.Writer) error {
+	_, err := io.WriteString(w, n.Snapshot().ANSI())
+	return err
+}
+
+// HTML returns the grid as a pre element with inline styles. The cursor is not
+// included.
+func (s Snapshot) HTML() string {
+	var b strings.Builder
+
+	bg, fg := hexColor(s.DefaultBg), hexColor(s.DefaultFg)
+	fmt.Fprintf(&b, `<pre style="font-family: monospace; background-color: %s; color: %s;">`,
+		bg, fg)
+
+	for i, row := range s.Cells {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for start := 0; start < len(row); {
+			end := start
+			for end+1 < len(row) && row[end+1].sameStyle(row[start]) {
+				end++
+			}
+
+			var text strings.Builder
+			for _, c := range row[start : end+1] {
+				text.WriteString(c.Text)
+			}
+			fmt.Fprintf(&b, `<span style="%s">%s</span>`, row[start].css(),
+				html.EscapeString(text.String()))
+
+			start = end + 1
+		}
+	}
+	b.WriteString("</pre>\n")
+
+	return b.String()
+}
+
+// Returns the inline CSS which sets all colors and styles of the cell
+func (c SnapshotCell) css() string {
+	rules := []string{
+		"color: " + hexColor(c.Fg),
+		"background-color: " + hexColor(c.Bg),
+	}
+	if c.Bold {
+		rules = append(rules, "font-weight: bold")
+	}
+	if c.Italic {
+		rules = append(rules, "font-style: italic")
+	}
+
+	var decorations []string
+	if c.Strikethrough {
+		decorations = append(decorations, "line-through")
+	}
+	if c.Underline != "" {
+		decorations = append(decorations, "underline")
+		switch c.Underline {
+		case "undercurl":
+			decorations = append(decorations, "wavy")
+		case "underdouble":
+			decorations = append(decorations, "double")
+		case "underdotted":
+			decorations = append(decorations, "dotted")
+		case "underdashed":
+			decorations = append(decorations, "dashed")
+		}
+		decorations = append(decorations, hexColor(c.Special))
+	}
+	if len(decorations) > 0 {
+		rules = append(rules, "text-decoration: "+strings.Join(decorations, " "))
+	}
+
+	return strings.Join(rules, "; ")
+}
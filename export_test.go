@@ -0,0 +1,71 @@
+package nvim_test
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+func TestExportPNG(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	drawScene(peer)
+
+	// the grid sizes the widget, so make the canvas show all of it
+	c := fyne.CurrentApp().Driver().CanvasForObject(n)
+	c.(test.WindowlessCanvas).Resize(n.Size())
+
+	var buf bytes.Buffer
+	err := n.ExportPNG(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	img, err := png.Decode(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int(n.Size().Width), img.Bounds().Dx())
+	assert.Equal(t, int(n.Size().Height), img.Bounds().Dy())
+}
+
+func TestExportHTML(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	drawScene(peer)
+	peer.Redraw(
+		nvimtest.GridLine(2, 0, nvimtest.Cells("a<b>&", 0)...),
+		nvimtest.Flush(),
+	)
+
+	var buf bytes.Buffer
+	err := n.ExportHTML(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	html := buf.String()
+	assert.True(t, strings.HasPrefix(html, "<pre "), "starts with pre: %q", html)
+	assert.Contains(t, html, `<span style="color: #ff0000; background-color: #000000; font-weight: bold">fun</span>`)
+	assert.Contains(t, html, "text-decoration: underline wavy #ff0000")
+	assert.Contains(t, html, "a&lt;b&gt;&amp;")
+	assert.Equal(t, 4, strings.Count(html, "\n"), "one line per row")
+}
+
+func TestExportANSI(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	drawScene(peer)
+
+	var buf bytes.Buffer
+	err := n.ExportANSI(&buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, n.Snapshot().ANSI(), buf.String())
+	assert.Contains(t, buf.String(), "\x1b[0;1;38;2;255;0;0;48;2;0;0;0mfun")
+}
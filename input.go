@@ -24,28 +24,37 @@ func (n *NeoVim) Tapped(ev *fyne.PointEvent) {
 // FocusGained is a hook called by the focus handling logic after this object
 // gained the focus.
 func (n *NeoVim) FocusGained() {
+	n.focused = true
 	n.Refresh()
+	n.notifyFocus(true)
 }
 
 // FocusGained implements fyne.Focusable
 // FocusLost is a hook called by the focus handling logic after this object lost
 // the focus.
 func (n *NeoVim) FocusLost() {
+	n.focused = false
 	n.Refresh()
+	n.notifyFocus(false)
 }
 
 // FocusGained implements fyne.Focusable
 // TypedRune is a hook called by the input handling logic on text input events
 // if this object is focused.
 func (n *NeoVim) TypedRune(r rune) {
-	n.Engine.Input(string(r))
+	// nvim_input takes < as the start of a key like <CR>
+	if r == '<' {
+		n.input("<LT>")
+		return
+	}
+	n.input(string(r))
 }
 
 // FocusGained implements fyne.Focusable
 // TypedKey is a hook called by the input handling logic on key events if this
 // object is focused.
 func (n *NeoVim) TypedKey(e *fyne.KeyEvent) {
-	n.Engine.Input(neovimKeyMap[e.Name])
+	n.input(neovimKeyMap[e.Name])
 }
 
 // Declare conformity with the shortcut interface
@@ -57,6 +66,11 @@ var _ fyne.Shortcutable = (*NeoVim)(nil)
 // TypedShortcut handle the registered shortcut
 // TODO : There are other shortcuts e.g. SelectAll (Cmd+A)
 func (n *NeoVim) TypedShortcut(s fyne.Shortcut) {
+	if s.ShortcutName() == DebugOverlayShortcut.ShortcutName() {
+		n.SetDebugOverlay(!n.DebugOverlayShown())
+		return
+	}
+
 	if ds, ok := s.(*desktop.CustomShortcut); ok {
 
 		char := ds.KeyName[0]
@@ -67,6 +81,27 @@ func (n *NeoVim) TypedShortcut(s fyne.Shortcut) {
 		}
 
 		modifiers := neovimModifierMap[ds.Modifier]
-		n.Engine.Input("<" + modifiers + string(char) + ">")
+		n.input("<" + modifiers + string(char) + ">")
+	}
+}
+
+// Tells nvim whether we have the focus, if NotifyFocus is set
+func (n *NeoVim) notifyFocus(gained bool) {
+	if n.Engine == nil || !n.NotifyFocus {
+		return
 	}
+
+	err := n.Engine.SetFocusUI(gained)
+	if err != nil {
+		n.log().Warn("setting focus failed", "gained", gained, "err", err)
+	}
+}
+
+// Sends keys to neovim, if the widget is attached to one
+func (n *NeoVim) input(keys string) {
+	if n.Engine == nil {
+		return
+	}
+
+	n.Engine.Input(keys)
 }
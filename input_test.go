@@ -0,0 +1,44 @@
+package nvim_test
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/stretchr/testify/assert"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+// Returns every key sequence sent to nvim_input
+func inputs(peer *nvimtest.Peer) []string {
+	var keys []string
+	for _, args := range peer.Calls("nvim_input") {
+		keys = append(keys, args[0].(string))
+	}
+	return keys
+}
+
+func TestTypedRune(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	n.TypedRune('a')
+	n.TypedRune('<')
+	assert.Equal(t, []string{"a", "<LT>"}, inputs(peer))
+}
+
+func TestTypedKey(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	n.TypedKey(&fyne.KeyEvent{Name: fyne.KeyEscape})
+	n.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+	n.TypedKey(&fyne.KeyEvent{Name: fyne.KeyF5})
+	assert.Equal(t, []string{"<Esc>", "<CR>", "<F5>"}, inputs(peer))
+}
+
+func TestTypedShortcut(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	n.TypedShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyW, Modifier: fyne.KeyModifierControl})
+	n.TypedShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyA, Modifier: fyne.KeyModifierShift | fyne.KeyModifierAlt})
+	assert.Equal(t, []string{"<C-W>", "<S-A-A>"}, inputs(peer))
+}
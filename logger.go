@@ -0,0 +1,105 @@
+package nvim
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger receives what the widget has to report, e.g. errors talking to nvim
+// or events it doesn't understand. args are alternating keys and values like
+// "event", "grid_line", "err", err. The methods match those of *slog.Logger,
+// so one can be used directly.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// LogLevel is the severity of a log message
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Used when no Logger is set, reports everything but debug messages
+var defaultLogger = NewTextLogger(os.Stderr, LogInfo)
+
+// Writes one line of key=value pairs per message, similar to slog's
+// TextHandler
+type textLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	min LogLevel
+}
+
+// Creates a Logger which writes messages of at least the given level to w,
+// one line per message. Use io.Discard to silence the widget.
+func NewTextLogger(w io.Writer, min LogLevel) Logger {
+	return &textLogger{w: w, min: min}
+}
+
+func (l *textLogger) Debug(msg string, args ...any) { l.log(LogDebug, msg, args) }
+func (l *textLogger) Info(msg string, args ...any)  { l.log(LogInfo, msg, args) }
+func (l *textLogger) Warn(msg string, args ...any)  { l.log(LogWarn, msg, args) }
+func (l *textLogger) Error(msg string, args ...any) { l.log(LogError, msg, args) }
+
+func (l *textLogger) log(level LogLevel, msg string, args []any) {
+	if level < l.min {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "time=%s level=%s msg=%q", time.Now().Format(time.RFC3339Nano),
+		level, msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			fmt.Fprintf(&b, " !BADKEY=%s", logValue(args[i]))
+			break
+		}
+		fmt.Fprintf(&b, " %v=%s", args[i], logValue(args[i+1]))
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, b.String())
+}
+
+// Formats a value, quoting it if it contains spaces or quotes
+func logValue(v any) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// Returns the logger to use
+func (n *NeoVim) log() Logger {
+	if n.Logger != nil {
+		return n.Logger
+	}
+	return defaultLogger
+}
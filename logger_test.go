@@ -0,0 +1,77 @@
+package nvim_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+// Remembers every message as "LEVEL msg key=value ..."
+type memLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *memLogger) Debug(msg string, args ...any) { l.log("DEBUG", msg, args) }
+func (l *memLogger) Info(msg string, args ...any)  { l.log("INFO", msg, args) }
+func (l *memLogger) Warn(msg string, args ...any)  { l.log("WARN", msg, args) }
+func (l *memLogger) Error(msg string, args ...any) { l.log("ERROR", msg, args) }
+
+func (l *memLogger) log(level, msg string, args []any) {
+	line := level + " " + msg
+	for i := 0; i+1 < len(args); i += 2 {
+		line += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, line)
+}
+
+func (l *memLogger) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+func TestTextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := nvim.NewTextLogger(&buf, nvim.LogInfo)
+
+	l.Debug("hidden")
+	l.Info("shown", "event", "grid_line", "err", "not a number")
+	l.Error("odd", "key")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+	assert.Contains(t, lines[0], ` level=INFO msg="shown" event=grid_line err="not a number"`)
+	assert.Contains(t, lines[1], ` level=ERROR msg="odd" !BADKEY=key`)
+}
+
+func TestLoggerEvents(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	l := &memLogger{}
+	n.Logger = l
+
+	peer.Redraw(
+		nvimtest.GridResize(16, 4),
+		nvimtest.Event("no_such_event", []interface{}{}),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"foreground": "red", "bold": true}),
+		nvimtest.Event("grid_cursor_goto", []interface{}{int64(1), "0", int64(0)}),
+	)
+
+	messages := l.Messages()
+	assert.Contains(t, messages, "DEBUG redraw event event=no_such_event calls=1")
+	assert.Contains(t, messages, "DEBUG ignoring unknown event event=no_such_event")
+	assert.Contains(t, messages, "WARN invalid highlight attribute event=hl_attr_define id=1 "+
+		"err=unexpected type of foreground=red (string)")
+	assert.Contains(t, messages, "WARN expected a number event=grid_cursor_goto value=0 type=string")
+}
@@ -0,0 +1,110 @@
+package nvim
+
+import (
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// The size of a cell and the position of the baseline within it, in canvas
+// units. The height is snapped to whole pixels at the scale it was measured
+// for, so rows never start in the middle of a pixel. The width is the exact
+// advance of the font, as runs of cells are drawn as a single text which
+// would drift off the grid otherwise.
+type cellMetrics struct {
+	Width, Height   float32
+	Ascent, Descent float32
+}
+
+// What the metrics depend on, they are measured again if any of it changes
+type metricsKey struct {
+	font  string // name of the font resource
+	size  float32
+	scale float32
+}
+
+// Measures the cells of the monospace font as the driver renders it
+func measureCell(key metricsKey) cellMetrics {
+	style := fyne.TextStyle{Monospace: true}
+	size, baseline := fyne.CurrentApp().Driver().RenderedTextSize("M", key.size, style)
+
+	snap := func(v float32) float32 {
+		return float32(math.Ceil(float64(v*key.scale))) / key.scale
+	}
+	ascent := snap(baseline)
+	height := snap(size.Height)
+
+	return cellMetrics{
+		Width:   size.Width,
+		Height:  height,
+		Ascent:  ascent,
+		Descent: height - ascent,
+	}
+}
+
+// Returns the metrics of a cell for the current theme and the scale of the
+// canvas showing the widget, measuring them only if either changed
+func (n *NeoVim) cellMetrics() cellMetrics {
+	key := metricsKey{
+		font:  theme.TextMonospaceFont().Name(),
+		size:  theme.TextSize(),
+		scale: n.scale(),
+	}
+
+	n.metricsMu.Lock()
+	defer n.metricsMu.Unlock()
+
+	if key != n.metricsKey {
+		n.metrics = measureCell(key)
+		n.metricsKey = key
+	}
+	return n.metrics
+}
+
+// Returns the scale of the canvas showing the widget, 1 if it isn't shown
+func (n *NeoVim) scale() float32 {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return 1
+	}
+	c := app.Driver().CanvasForObject(n)
+	if c == nil || c.Scale() <= 0 {
+		return 1
+	}
+	return c.Scale()
+}
+
+// Returns how many rows and columns fit into size
+func (m cellMetrics) gridSize(size fyne.Size) (rows, cols int) {
+	// a size calculated by pixelSize may be a tiny bit smaller due to the
+	// float arithmetic, which must not cost a whole cell
+	const epsilon = 1e-3
+	rows = int(size.Height/m.Height + epsilon)
+	cols = int(size.Width/m.Width + epsilon)
+	return rows, cols
+}
+
+// Returns the size of a grid with the given rows and columns
+func (m cellMetrics) pixelSize(rows, cols int) fyne.Size {
+	return fyne.NewSize(float32(cols)*m.Width, float32(rows)*m.Height)
+}
+
+// Returns where the first cell of a grid with the given rows and columns is
+// drawn in the widget, i.e. behind the padding and, if the grid is centered,
+// half of the space left by the cells. It is snapped to whole pixels, so the
+// rows are too.
+func (n *NeoVim) gridOrigin(m cellMetrics, rows, cols int) fyne.Position {
+	origin := fyne.NewPos(n.Padding, n.Padding)
+	if n.CenterGrid {
+		free := n.Size().Subtract(m.pixelSize(rows, cols))
+		origin.X = float32(math.Max(float64(free.Width/2), float64(origin.X)))
+		origin.Y = float32(math.Max(float64(free.Height/2), float64(origin.Y)))
+	}
+
+	scale := n.scale()
+	snap := func(v float32) float32 {
+		return float32(math.Round(float64(v*scale))) / scale
+	}
+	return fyne.NewPos(snap(origin.X), snap(origin.Y))
+}
@@ -0,0 +1,68 @@
+package nvim
+
+import (
+	"math"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"github.com/stretchr/testify/assert"
+)
+
+// The default theme with a larger text size
+type largeTextTheme struct {
+	fyne.Theme
+}
+
+func (t largeTextTheme) Size(name fyne.ThemeSizeName) float32 {
+	if name == theme.SizeNameText {
+		return 2 * t.Theme.Size(name)
+	}
+	return t.Theme.Size(name)
+}
+
+func TestCellMetrics(t *testing.T) {
+	test.NewApp()
+	defer test.NewApp()
+
+	n := NewUnattached()
+	w := test.NewWindow(n)
+	defer w.Close()
+
+	m := n.cellMetrics()
+	assert.Greater(t, m.Width, float32(0))
+	assert.Greater(t, m.Ascent, float32(0))
+	assert.Greater(t, m.Descent, float32(0))
+	assert.Equal(t, m.Height, m.Ascent+m.Descent)
+
+	// rows start at whole pixels at any scale
+	w.Canvas().(test.WindowlessCanvas).SetScale(1.5)
+	m = n.cellMetrics()
+	assert.Equal(t, float32(1.5), n.metricsKey.scale)
+	height := float64(m.Height * 1.5)
+	assert.InDelta(t, math.Round(height), height, 1e-3)
+
+	test.ApplyTheme(t, largeTextTheme{theme.DefaultTheme()})
+	large := n.cellMetrics()
+	assert.Greater(t, large.Width, m.Width)
+	assert.Greater(t, large.Height, m.Height)
+}
+
+func TestCellMetricsGridSize(t *testing.T) {
+	m := cellMetrics{Width: 7.2265625, Height: 17, Ascent: 13, Descent: 4}
+
+	for rows := 1; rows < 100; rows++ {
+		for cols := 1; cols < 300; cols += 7 {
+			r, c := m.gridSize(m.pixelSize(rows, cols))
+			if r != rows || c != cols {
+				t.Fatalf("%dx%d cells were resized to %dx%d", cols, rows, c, r)
+			}
+		}
+	}
+
+	// partial cells don't count
+	r, c := m.gridSize(fyne.NewSize(m.Width*10.5, m.Height*3.9))
+	assert.Equal(t, 3, r)
+	assert.Equal(t, 10, c)
+}
@@ -1,12 +1,11 @@
 package nvim
 
 import (
-	"fmt"
 	"image/color"
-	"math"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/widget"
 	"github.com/neovim/go-client/nvim"
 )
@@ -46,7 +45,8 @@ type highlight struct {
 	Blend   interface{} `map:"blend"`
 }
 
-var defaultHL = highlight{
+// The default colors until nvim sets its own
+var initialDefaultHL = highlight{
 	Fg:      color.RGBA{255, 255, 255, 255},
 	Bg:      color.RGBA{0, 0, 0, 255},
 	Special: color.RGBA{0, 0, 0, 255},
@@ -62,43 +62,230 @@ type NeoVim struct {
 	// Additional fields
 	// It is standard in a Fyne widget to export the fields which define
 	// behaviour (just like the primitives defined in the canvas package).
-	Engine                     *nvim.Nvim
-	content                    *widget.TextGrid
-	cursorRow, cursorCol       int
-	cursorCellFg, cursorCellBg color.Color       // store color of the underlying cell
-	hl                         map[int]highlight // the highlight table used by ext_hlstate
+	Engine               *nvim.Nvim
+	OnExit               func(code int)      // see Options.OnExit
+	Logger               Logger              // nil logs to stderr, see Options.Logger
+	Padding              float32             // see Options.Padding
+	CenterGrid           bool                // see Options.CenterGrid
+	ScrollAnimation      time.Duration       // see Options.ScrollAnimation
+	CursorAnimation      time.Duration       // see Options.CursorAnimation
+	CursorEasing         fyne.AnimationCurve // see Options.CursorAnimation
+	CursorTrail          bool                // see Options.CursorAnimation
+	UnfocusedCursor      UnfocusedCursor     // see Options.UnfocusedCursor
+	NotifyFocus          bool                // see Options.NotifyFocus
+	Transparency         float32             // see Options.Transparency
+	Ligatures            bool                // see Options.Ligatures
+	FontFeatures         []string            // see Options.Ligatures
+	FallbackFonts        []fyne.Resource     // see Options.FallbackFonts
+	EmojiFont            fyne.Resource       // see Options.EmojiFont
+	cells                [][]gridCell        // the grid as sent by nvim
+	dirty                []bool              // rows changed since the last refresh
+	cursorRow, cursorCol int
+	defaultHL            highlight          // the default colors as set by default_colors_set
+	gridMu               sync.Mutex         // guards cells, dirty, the cursor position and defaultHL
+	focused              bool               // the cursor is hollow otherwise
+	hl                   map[int]highlight  // the highlight table used by ext_hlstate
+	styles               map[int]*gridStyle // cache of the cell styles per hl id
+	modeInfo             []modeInfo         // cursor styles as set by mode_info_set
+	modeIdx              int                // index of the current mode in modeInfo
+	mode                 string             // name of the current mode
+	recorder             *redrawRecorder    // set while recording redraws
+	cast                 *castRecorder      // set while recording asciicast
+	recorderMu           sync.Mutex         // guards recorder and cast
+	debug                debugStats         // shown by the debug overlay
+	metrics              cellMetrics        // cached by cellMetrics
+	metricsKey           metricsKey         // what metrics were measured for
+	metricsMu            sync.Mutex         // guards metrics and metricsKey
+	available            fyne.Size          // the size given by Resize
+	availableMu          sync.Mutex         // guards available
+	scrolls              []pendingScroll    // to be animated by the next refresh
+	scrollMu             sync.Mutex         // guards scrolls
+}
+
+// Describes how the cursor looks in a mode, as sent by mode_info_set
+type modeInfo struct {
+	Name           string
+	ShortName      string
+	CursorShape    string // "block", "horizontal" or "vertical"
+	CellPercentage int    // size of the cursor in % of the cell
+	AttrID         int    // highlight of the cursor, 0 for inverted colors
+}
+
+// Options configure how the Neovim instance behind the widget is started
+type Options struct {
+	// Command is the nvim executable to start, defaults to "nvim" from PATH
+	Command string
+
+	// Args are passed to nvim in addition to --embed e.g. files to open,
+	// "+cmd" or "-u NONE". They are ignored when attaching to a Server.
+	Args []string
+
+	// Server is the address of an already running nvim (see :help --listen)
+	// to attach to instead of starting a child process
+	Server string
+
+	// OnExit is called with the exit code of nvim once it exited. Unlike
+	// setting the field of the widget afterwards, this also catches nvim
+	// exiting right away e.g. because of an error in its config.
+	OnExit func(code int)
+
+	// Logger receives errors, warnings and, if it is enabled for them, debug
+	// traces of every event received from nvim. Defaults to writing everything
+	// but debug messages to stderr.
+	Logger Logger
+
+	// Padding is the space kept free on every side of the grid. It is drawn
+	// in the default background like the part of the widget too small for
+	// another row or column of cells.
+	Padding float32
+
+	// CenterGrid splits the space too small for another row or column evenly
+	// between both sides, instead of leaving it at the right and bottom
+	CenterGrid bool
+
+	// ScrollAnimation is how long scrolling glides to the new lines instead of
+	// jumping there, zero disables it
+	ScrollAnimation time.Duration
+
+	// CursorAnimation is how long the cursor slides to where it moved instead
+	// of jumping there, zero disables it. CursorEasing is the curve it moves
+	// along, ease out if nil, and with CursorTrail it is followed by a fading
+	// trail, which helps keeping track of it e.g. on large screens.
+	CursorAnimation time.Duration
+	CursorEasing    fyne.AnimationCurve
+	CursorTrail     bool
+
+	// UnfocusedCursor is how the cursor looks while the widget doesn't have
+	// the focus, hollow by default
+	UnfocusedCursor UnfocusedCursor
+
+	// NotifyFocus tells nvim when the widget gains or loses the focus, which
+	// triggers the FocusGained and FocusLost autocommands used e.g. by
+	// 'autoread' to check for files changed outside of nvim
+	NotifyFocus bool
+
+	// Transparency lets whatever is behind the widget shine through the
+	// default background, from 0 for none to 1 for an invisible background.
+	// Cells with a background of their own stay opaque.
+	Transparency float32
+
+	// Ligatures joins characters like -> or != into a single glyph, if the
+	// font has such ligatures like Fira Code or JetBrains Mono do. Runs of
+	// cells in the same highlight are shaped with HarfBuzz then, with
+	// FontFeatures set as well. These are OpenType features in HarfBuzz
+	// syntax, e.g. "ss01" for a stylistic set or "-calt" to limit ligatures
+	// to the standard ones. FontFeatures without Ligatures shape the cells
+	// with the ligature features turned off.
+	Ligatures    bool
+	FontFeatures []string
+
+	// FallbackFonts are checked in order for glyphs the monospace font of the
+	// theme doesn't have, like icons of Nerd Fonts or CJK characters. Glyphs
+	// too wide for their cells are scaled down, narrower ones centered.
+	FallbackFonts []fyne.Resource
+
+	// EmojiFont draws emoji, e.g. Noto Color Emoji loaded with
+	// fyne.LoadResourceFromPath. Defaults to the emoji font bundled with fyne.
+	// Emoji are sized to fit the two cells nvim gives them and sit on the
+	// baseline of the text around them.
+	EmojiFont fyne.Resource
 }
 
 // Create a new NeoVim widget with the given path
 func New(pth string) *NeoVim {
-	neovim := &NeoVim{}
-	neovim.hl = make(map[int]highlight)
+	return NewWithOptions(pth, Options{})
+}
 
-	tgrid := widget.NewTextGrid()
-	neovim.content = tgrid
+// Create a new NeoVim widget with the given path, starting or attaching to
+// Neovim as described by opts
+func NewWithOptions(pth string, opts Options) *NeoVim {
+	neovim := newNeoVim()
+	neovim.SetOptions(opts)
+	err := neovim.startNeovim(pth, opts)
+	if err != nil {
+		neovim.log().Error("starting neovim failed", "err", err)
+	}
 
-	neovim.ExtendBaseWidget(neovim)
-	err := neovim.startNeovim(pth)
+	return neovim
+}
+
+// SetOptions sets the fields of the widget from opts, e.g. to set up a widget
+// created by NewUnattached like one created by NewWithOptions. Command, Args
+// and Server are left out, as they only matter for starting nvim. Like the
+// fields, the options take effect with the next refresh.
+func (n *NeoVim) SetOptions(opts Options) {
+	n.OnExit = opts.OnExit
+	n.Logger = opts.Logger
+	n.Padding = opts.Padding
+	n.CenterGrid = opts.CenterGrid
+	n.ScrollAnimation = opts.ScrollAnimation
+	n.CursorAnimation = opts.CursorAnimation
+	n.CursorEasing = opts.CursorEasing
+	n.CursorTrail = opts.CursorTrail
+	n.UnfocusedCursor = opts.UnfocusedCursor
+	n.NotifyFocus = opts.NotifyFocus
+	n.Transparency = opts.Transparency
+	n.Ligatures = opts.Ligatures
+	n.FontFeatures = opts.FontFeatures
+	n.FallbackFonts = opts.FallbackFonts
+	n.EmojiFont = opts.EmojiFont
+}
+
+// Create a new NeoVim widget on top of an existing connection to nvim, e.g.
+// one created with nvim.New over custom pipes. The widget serves the
+// connection itself, so the caller must not call Serve.
+func NewWithEngine(engine *nvim.Nvim) *NeoVim {
+	neovim := newNeoVim()
+	err := neovim.attach(engine, "", false)
 	if err != nil {
-		fmt.Println("Error starting neovim: ", err)
+		neovim.log().Error("attaching to neovim failed", "err", err)
 	}
 
 	return neovim
 }
 
+// Create a new NeoVim widget which is not attached to any nvim, e.g. to show
+// redraws replayed with ReplayRedraws
+func NewUnattached() *NeoVim {
+	return newNeoVim()
+}
+
+// Helper to create the widget without any neovim attached yet
+func newNeoVim() *NeoVim {
+	neovim := &NeoVim{defaultHL: initialDefaultHL}
+	neovim.hl = make(map[int]highlight)
+	neovim.styles = make(map[int]*gridStyle)
+	neovim.debug.current.start = time.Now()
+
+	neovim.ExtendBaseWidget(neovim)
+	return neovim
+}
+
 // Helper to start neovim
-func (n *NeoVim) startNeovim(pth string) error {
-	// start neovim
-	// --embed to use stdin/stdout as a msgpack-RPC channel
-	opt := nvim.ChildProcessArgs("--embed")
-	nvimInstance, err := nvim.NewChildProcess(opt)
+func (n *NeoVim) startNeovim(pth string, opts Options) error {
+	nvimInstance, err := connectNeovim(opts)
 	if err != nil {
 		return err
 	}
 
-	err = nvimInstance.SetCurrentDirectory(pth)
-	if err != nil {
-		fmt.Println("Error setting project: ", err)
+	return n.attach(nvimInstance, pth, opts.Server == "")
+}
+
+// Helper to attach the widget as UI to the given neovim
+func (n *NeoVim) attach(nvimInstance *nvim.Nvim, pth string, childProcess bool) error {
+	n.Engine = nvimInstance
+	go n.serve(childProcess)
+
+	nvimInstance.RegisterHandler("redraw", func(events ...[]interface{}) {
+		n.recordRedraw(events)
+		n.handleRedraw(events)
+	})
+
+	if pth != "" {
+		err := nvimInstance.SetCurrentDirectory(pth)
+		if err != nil {
+			n.log().Error("setting project failed", "path", pth, "err", err)
+		}
 	}
 
 	// tell nvim we want to draw the screen (using the new line based API)
@@ -106,51 +293,107 @@ func (n *NeoVim) startNeovim(pth string) error {
 	uiOpt["ext_hlstate"] = true  // detailed highlight state
 	uiOpt["ext_linegrid"] = true // new line based grid events
 	uiOpt["ext_multigrid"] = false
-	err = nvimInstance.AttachUI(MIN_COLS, MIN_ROWS, uiOpt)
+	err := nvimInstance.AttachUI(MIN_COLS, MIN_ROWS, uiOpt)
 	if err != nil {
-		fmt.Println("Error attaching UI: ", err)
+		n.log().Error("attaching UI failed", "err", err)
 		return err
 	}
 
-	nvimInstance.RegisterHandler("redraw", func(events ...[]interface{}) {
-		for _, event := range events {
-			n.HandleNvimEvent(event)
-		}
-	})
+	return nil
+}
 
-	n.Engine = nvimInstance
+// Handles a batch of redraw events
+func (n *NeoVim) handleRedraw(events [][]interface{}) {
+	start := time.Now()
+	for _, event := range events {
+		n.log().Debug("redraw event", "event", event[0], "calls", len(event)-1)
+		n.HandleNvimEvent(event)
+	}
+	rows, cols := n.GridSize()
+	n.debug.addBatch(events, time.Since(start), debugGrid{rows, cols, n.mode, n.cursorShape()})
+}
 
-	return nil
+// Serves the RPC connection until nvim exits or disconnects and reports the
+// exit code to OnExit. Only a child process has an exit code, so attaching to
+// a server always reports 0.
+func (n *NeoVim) serve(childProcess bool) {
+	err := n.Engine.Serve()
+	if err != nil {
+		n.log().Error("serving neovim failed", "err", err)
+	}
+
+	code := 0
+	if childProcess {
+		code = n.Engine.ExitCode()
+	}
+
+	if n.OnExit != nil {
+		n.OnExit(code)
+	}
+}
+
+// Helper to either start neovim as a child process or dial a running one
+func connectNeovim(opts Options) (*nvim.Nvim, error) {
+	// serving is done by the widget itself to notice when nvim exits
+	if opts.Server != "" {
+		return nvim.Dial(opts.Server, nvim.DialServe(false))
+	}
+
+	// --embed to use stdin/stdout as a msgpack-RPC channel
+	args := append([]string{"--embed"}, opts.Args...)
+	cpOpts := []nvim.ChildProcessOption{
+		nvim.ChildProcessArgs(args...),
+		nvim.ChildProcessServe(false),
+	}
+	if opts.Command != "" {
+		cpOpts = append(cpOpts, nvim.ChildProcessCommand(opts.Command))
+	}
+
+	return nvim.NewChildProcess(cpOpts...)
 }
 
 // Override resize to adjust the textgrid
 func (n *NeoVim) Resize(s fyne.Size) {
+	n.availableMu.Lock()
+	n.available = s
+	n.availableMu.Unlock()
+
 	n.resizeGrid(s)
 }
 
+// Returns the size the widget was last given by Resize, which is what the grid
+// is fitted into
+func (n *NeoVim) availableSize() fyne.Size {
+	n.availableMu.Lock()
+	defer n.availableMu.Unlock()
+
+	return n.available
+}
+
+// Returns the rows and columns fitting into a widget of size s, within the
+// padding
+func (n *NeoVim) fitGrid(s fyne.Size) (rows, cols int) {
+	inner := s.SubtractWidthHeight(2*n.Padding, 2*n.Padding).Max(fyne.NewSize(0, 0))
+	return n.cellMetrics().gridSize(inner)
+}
+
 // Resizes the neovim internal grid
 func (n *NeoVim) resizeGrid(s fyne.Size) {
-	cellSize := guessCellSize()
-	rowsCnt := int(s.Height / cellSize.Height)
-	colsCnt := int(s.Width / cellSize.Width)
+	if n.Engine == nil {
+		return
+	}
+
+	rowsCnt, colsCnt := n.fitGrid(s)
 
 	// Triggers the resize event
 	err := n.Engine.TryResizeUIGrid(GLOBAL_GRID, colsCnt, rowsCnt)
 	if err != nil {
-		fmt.Println("Error resizing grid: ", err)
+		n.log().Error("resizing grid failed", "grid", GLOBAL_GRID,
+			"cols", colsCnt, "rows", rowsCnt, "err", err)
 	}
 }
 
 // CreateRenderer implements fyne.Widget
 func (n *NeoVim) CreateRenderer() fyne.WidgetRenderer {
-	return &render{n}
-}
-
-// Helper to estimate the size of a cell in the textgrid
-func guessCellSize() fyne.Size {
-	cell := canvas.NewText("M", color.White)
-	cell.TextStyle.Monospace = true
-
-	min := cell.MinSize()
-	return fyne.NewSize(float32(math.Round(float64(min.Width))), float32(math.Round(float64(min.Height))))
+	return newRender(n)
 }
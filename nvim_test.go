@@ -1,7 +1,13 @@
 package nvim
 
 import (
+	"io"
+	"reflect"
 	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,3 +16,68 @@ func TestNewTerminal(t *testing.T) {
 	nvim := New("")
 	assert.NotNil(t, nvim)
 }
+
+func TestOnExitRightAway(t *testing.T) {
+	exited := make(chan int, 1)
+	// exits before the UI is even attached
+	NewWithOptions("", Options{
+		Command: "false",
+		Logger:  NewTextLogger(io.Discard, LogError),
+		OnExit:  func(code int) { exited <- code },
+	})
+
+	select {
+	case code := <-exited:
+		assert.Equal(t, 1, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnExit was not called")
+	}
+}
+
+func TestSetOptions(t *testing.T) {
+	opts := Options{
+		Command:         "nvim",
+		Args:            []string{"--clean"},
+		Server:          "localhost:6666",
+		OnExit:          func(int) {},
+		Logger:          NewTextLogger(io.Discard, LogError),
+		Padding:         4,
+		CenterGrid:      true,
+		ScrollAnimation: time.Second,
+		CursorAnimation: time.Second,
+		CursorEasing:    fyne.AnimationLinear,
+		CursorTrail:     true,
+		UnfocusedCursor: CursorHidden,
+		NotifyFocus:     true,
+		Transparency:    0.5,
+		Ligatures:       true,
+		FontFeatures:    []string{"ss01"},
+		FallbackFonts:   []fyne.Resource{theme.DefaultTextFont()},
+		EmojiFont:       theme.DefaultEmojiFont(),
+	}
+	n := NewUnattached()
+	n.SetOptions(opts)
+
+	// every option but those starting nvim ends up in the field of its name
+	o, w := reflect.ValueOf(opts), reflect.ValueOf(n).Elem()
+	for i := 0; i < o.NumField(); i++ {
+		name := o.Type().Field(i).Name
+		if !assert.False(t, o.Field(i).IsZero(), "%s is not set by the test", name) {
+			continue
+		}
+		switch name {
+		case "Command", "Args", "Server":
+			continue
+		}
+
+		field := w.FieldByName(name)
+		if !assert.True(t, field.IsValid(), "no field %s", name) {
+			continue
+		}
+		if field.Kind() == reflect.Func {
+			assert.False(t, field.IsNil(), "%s", name)
+			continue
+		}
+		assert.Equal(t, o.Field(i).Interface(), field.Interface(), "%s", name)
+	}
+}
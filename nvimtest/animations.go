@@ -0,0 +1,93 @@
+package nvimtest
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// Animations holds back the animations the widget starts, which fyne's test
+// driver would finish right away, so tests can check how they look halfway
+type Animations struct {
+	mu      sync.Mutex
+	running []*fyne.Animation
+}
+
+// HoldAnimations makes the animations started from now on wait for Tick. It
+// has to be called after New, which sets up the app it wraps.
+func HoldAnimations() *Animations {
+	a := &Animations{}
+	app := fyne.CurrentApp()
+	fyne.SetCurrentApp(&holdingApp{app, &holdingDriver{app.Driver(), a}})
+	return a
+}
+
+// Running returns how many animations were started and are not finished
+func (a *Animations) Running() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.running)
+}
+
+// Tick advances all running animations to progress, from 0 at their start to
+// 1 at their end, along their curves like the driver does. Animations ticked
+// at their end are finished.
+func (a *Animations) Tick(progress float32) {
+	a.mu.Lock()
+	running := append([]*fyne.Animation(nil), a.running...)
+	if progress >= 1 {
+		a.running = nil
+	}
+	a.mu.Unlock()
+
+	for _, anim := range running {
+		p := progress
+		if anim.Curve != nil {
+			p = anim.Curve(p)
+		}
+		anim.Tick(p)
+	}
+}
+
+func (a *Animations) start(anim *fyne.Animation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.running = append(a.running, anim)
+}
+
+func (a *Animations) stop(anim *fyne.Animation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i, running := range a.running {
+		if running == anim {
+			a.running = append(a.running[:i], a.running[i+1:]...)
+			return
+		}
+	}
+}
+
+// The test app with a driver holding back animations
+type holdingApp struct {
+	fyne.App
+	driver fyne.Driver
+}
+
+func (a *holdingApp) Driver() fyne.Driver {
+	return a.driver
+}
+
+type holdingDriver struct {
+	fyne.Driver
+	anims *Animations
+}
+
+func (d *holdingDriver) StartAnimation(anim *fyne.Animation) {
+	d.anims.start(anim)
+}
+
+func (d *holdingDriver) StopAnimation(anim *fyne.Animation) {
+	d.anims.stop(anim)
+}
@@ -0,0 +1,123 @@
+package nvimtest
+
+import "image/color"
+
+// Helpers to build redraw events the way they arrive from nvim, i.e. as the
+// event name followed by one argument list per call. Numbers are int64 like
+// the msgpack decoder produces them for small values.
+// See https://neovim.io/doc/user/ui.html for the meaning of the events.
+
+// Event builds an event with the given name and argument lists
+func Event(name string, calls ...[]interface{}) []interface{} {
+	event := []interface{}{name}
+	for _, c := range calls {
+		event = append(event, c)
+	}
+	return event
+}
+
+// Cell builds a grid_line cell, hl and repeat are optional
+func Cell(text string, hlAndRepeat ...int) []interface{} {
+	cell := []interface{}{text}
+	for _, v := range hlAndRepeat {
+		cell = append(cell, int64(v))
+	}
+	return cell
+}
+
+// Cells builds one grid_line cell per character of text, all using hl
+func Cells(text string, hl int) [][]interface{} {
+	var cells [][]interface{}
+	for _, r := range text {
+		cells = append(cells, Cell(string(r)))
+	}
+	if len(cells) > 0 {
+		cells[0] = Cell(cells[0][0].(string), hl)
+	}
+	return cells
+}
+
+// GridResize builds a grid_resize event for the global grid
+func GridResize(width, height int) []interface{} {
+	return Event("grid_resize", []interface{}{int64(1), int64(width), int64(height)})
+}
+
+// GridLine builds a grid_line event writing cells starting at row and col
+func GridLine(row, col int, cells ...[]interface{}) []interface{} {
+	c := make([]interface{}, len(cells))
+	for i, cell := range cells {
+		c[i] = cell
+	}
+	return Event("grid_line", []interface{}{int64(1), int64(row), int64(col), c, false})
+}
+
+// GridClear builds a grid_clear event for the global grid
+func GridClear() []interface{} {
+	return Event("grid_clear", []interface{}{int64(1)})
+}
+
+// GridCursorGoto builds a grid_cursor_goto event
+func GridCursorGoto(row, col int) []interface{} {
+	return Event("grid_cursor_goto", []interface{}{int64(1), int64(row), int64(col)})
+}
+
+// GridScroll builds a grid_scroll event
+func GridScroll(top, bot, left, right, rows int) []interface{} {
+	return Event("grid_scroll", []interface{}{int64(1), int64(top), int64(bot),
+		int64(left), int64(right), int64(rows), int64(0)})
+}
+
+// DefaultColorsSet builds a default_colors_set event
+func DefaultColorsSet(fg, bg, sp color.Color) []interface{} {
+	return Event("default_colors_set", []interface{}{RGB(fg), RGB(bg), RGB(sp),
+		int64(0), int64(0)})
+}
+
+// HLAttrDefine builds a hl_attr_define event, colors in rgbAttr may be given as
+// color.Color and are converted
+func HLAttrDefine(id int, rgbAttr map[string]interface{}) []interface{} {
+	attr := make(map[string]interface{}, len(rgbAttr))
+	for k, v := range rgbAttr {
+		if c, ok := v.(color.Color); ok {
+			v = RGB(c)
+		}
+		attr[k] = v
+	}
+
+	return Event("hl_attr_define", []interface{}{int64(id), attr,
+		map[string]interface{}{}, []interface{}{}})
+}
+
+// ModeInfoSet builds a mode_info_set event, each info describes a mode e.g.
+// {"name": "insert", "cursor_shape": "vertical", "cell_percentage": 25}
+func ModeInfoSet(infos ...map[string]interface{}) []interface{} {
+	list := make([]interface{}, len(infos))
+	for i, info := range infos {
+		m := make(map[string]interface{}, len(info))
+		for k, v := range info {
+			if num, ok := v.(int); ok {
+				v = int64(num)
+			}
+			m[k] = v
+		}
+		list[i] = m
+	}
+
+	return Event("mode_info_set", []interface{}{true, list})
+}
+
+// ModeChange builds a mode_change event
+func ModeChange(mode string, idx int) []interface{} {
+	return Event("mode_change", []interface{}{mode, int64(idx)})
+}
+
+// Flush builds a flush event
+func Flush() []interface{} {
+	return Event("flush", []interface{}{})
+}
+
+// RGB encodes a color as 0xRRGGBB like nvim does
+func RGB(c color.Color) int64 {
+	r, g, b, _ := c.RGBA()
+	return int64(r>>8)<<16 | int64(g>>8)<<8 | int64(b>>8)
+}
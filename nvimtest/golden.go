@@ -0,0 +1,83 @@
+package nvimtest
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+)
+
+// Run the tests with NVIMTEST_UPDATE=1 to (re)write the golden files instead
+// of comparing against them. An environment variable rather than a flag, which
+// would clash with the -update flag of tests importing this package.
+const updateEnv = "NVIMTEST_UPDATE"
+
+// Whether the golden files are to be written
+func update() bool {
+	return os.Getenv(updateEnv) != ""
+}
+
+// AssertSnapshot compares the text dump of n's snapshot against the golden file
+// testdata/<name>
+func AssertSnapshot(t *testing.T, n *nvim.NeoVim, name string) bool {
+	t.Helper()
+
+	got := n.Snapshot().String()
+	pth := filepath.Join("testdata", name)
+
+	if update() {
+		err := writeGolden(pth, []byte(got))
+		return assert.NoError(t, err)
+	}
+
+	want, err := os.ReadFile(pth)
+	if !assert.NoError(t, err, "missing golden file, run the tests with "+updateEnv+"=1") {
+		return false
+	}
+
+	return assert.Equal(t, string(want), got, "snapshot differs from %s", pth)
+}
+
+// AssertImage compares what the canvas containing n renders against the golden
+// image testdata/<name>. On mismatch fyne writes the rendered image to
+// testdata/failed/<name>.
+func AssertImage(t *testing.T, n *nvim.NeoVim, name string) bool {
+	t.Helper()
+
+	c := fyne.CurrentApp().Driver().CanvasForObject(n)
+	if !assert.NotNil(t, c, "widget is not shown on a canvas") {
+		return false
+	}
+
+	// the grid sizes the widget, so make the canvas match
+	if wc, ok := c.(test.WindowlessCanvas); ok {
+		wc.Resize(n.Size())
+	}
+	img := c.Capture()
+
+	if update() {
+		var buf bytes.Buffer
+		err := png.Encode(&buf, img)
+		if err == nil {
+			err = writeGolden(filepath.Join("testdata", name), buf.Bytes())
+		}
+		return assert.NoError(t, err)
+	}
+
+	return test.AssertImageMatches(t, name, img)
+}
+
+func writeGolden(pth string, content []byte) error {
+	err := os.MkdirAll(filepath.Dir(pth), 0o755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pth, content, 0o644)
+}
@@ -0,0 +1,171 @@
+// Package nvimtest provides a fake Neovim to test the NeoVim widget without an
+// nvim binary. The fake speaks msgpack-RPC over in-memory pipes, records the
+// requests the widget makes and sends scripted redraw batches to it.
+package nvimtest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/neovim/go-client/msgpack/rpc"
+	goclient "github.com/neovim/go-client/nvim"
+	nvim "github.com/yesoer/fyne-nvim"
+)
+
+// The notification used to find out whether all previous notifications have
+// been handled by the widget
+const syncMethod = "nvimtest_sync"
+
+// The API methods the widget is expected to call. Calls to other methods are
+// answered with an error, unless a handler was added with Handle.
+var recordedMethods = []string{
+	"nvim_ui_attach",
+	"nvim_ui_detach",
+	"nvim_ui_try_resize",
+	"nvim_ui_try_resize_grid",
+	"nvim_ui_set_focus",
+	"nvim_set_current_dir",
+	"nvim_input",
+	"nvim_command",
+}
+
+// Peer is the server side of the RPC connection, i.e. a fake nvim
+type Peer struct {
+	tb     testing.TB
+	ep     *rpc.Endpoint
+	synced chan struct{}
+
+	mu    sync.Mutex
+	calls map[string][][]interface{}
+}
+
+// New creates a NeoVim widget connected to a fake nvim. The widget is shown in
+// a window of fyne's test driver, the returned Peer is used to script nvim.
+// Everything is cleaned up once the test finished.
+func New(tb testing.TB) (*nvim.NeoVim, *Peer) {
+	tb.Helper()
+
+	test.NewApp()
+	tb.Cleanup(func() { test.NewApp() })
+
+	p := newPeer(tb)
+	serverConn, clientConn := net.Pipe()
+
+	ep, err := rpc.NewEndpoint(serverConn, serverConn, serverConn, rpc.WithLogf(tb.Logf))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	p.ep = ep
+	for _, method := range recordedMethods {
+		p.Handle(method, nil)
+	}
+
+	served := make(chan struct{})
+	go func() {
+		err := ep.Serve()
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			tb.Errorf("fake nvim: %v", err)
+		}
+		close(served)
+	}()
+
+	engine, err := goclient.New(clientConn, clientConn, clientConn, tb.Logf)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	err = engine.RegisterHandler(syncMethod, func() { p.synced <- struct{}{} })
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	n := nvim.NewWithEngine(engine)
+	w := test.NewWindow(n)
+	w.SetPadded(false)
+	tb.Cleanup(func() {
+		w.Close()
+		engine.Close()
+		ep.Close()
+		<-served
+	})
+
+	return n, p
+}
+
+func newPeer(tb testing.TB) *Peer {
+	return &Peer{
+		tb:     tb,
+		synced: make(chan struct{}),
+		calls:  make(map[string][][]interface{}),
+	}
+}
+
+// Handle records requests to method and answers them using fn. If fn is nil
+// requests are answered with nil.
+func (p *Peer) Handle(method string, fn func(args []interface{}) (interface{}, error)) {
+	err := p.ep.Register(method, func(args ...interface{}) (interface{}, error) {
+		p.mu.Lock()
+		p.calls[method] = append(p.calls[method], args)
+		p.mu.Unlock()
+
+		if fn == nil {
+			return nil, nil
+		}
+		return fn(args)
+	})
+	if err != nil {
+		p.tb.Fatal(err)
+	}
+}
+
+// Calls returns the arguments of every request to method so far
+func (p *Peer) Calls(method string) [][]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([][]interface{}(nil), p.calls[method]...)
+}
+
+// LastCall returns the arguments of the last request to method, ok is false if
+// there was none
+func (p *Peer) LastCall(method string) (args []interface{}, ok bool) {
+	calls := p.Calls(method)
+	if len(calls) == 0 {
+		return nil, false
+	}
+
+	return calls[len(calls)-1], true
+}
+
+// Redraw sends the events as a single redraw batch and returns once the widget
+// has handled all of them
+func (p *Peer) Redraw(events ...[]interface{}) {
+	p.tb.Helper()
+
+	args := make([]interface{}, len(events))
+	for i, e := range events {
+		args[i] = e
+	}
+
+	err := p.ep.Notify("redraw", args...)
+	if err != nil {
+		p.tb.Fatal(err)
+	}
+	p.Sync()
+}
+
+// Sync waits until the widget has handled every notification sent so far.
+// Notifications are handled in order, so once our own one arrived all
+// previous ones are done.
+func (p *Peer) Sync() {
+	p.tb.Helper()
+
+	err := p.ep.Notify(syncMethod)
+	if err != nil {
+		p.tb.Fatal(err)
+	}
+	<-p.synced
+}
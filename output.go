@@ -1,147 +1,269 @@
 package nvim
 
-import "fyne.io/fyne/v2/widget"
+import "image/color"
 
-// Substitutes all runes with ' '
+// Cell describes what is displayed in a single cell of the grid
+type Cell struct {
+	Text   string
+	Fg, Bg color.Color
+}
+
+// A cell of the grid as sent by nvim
+type gridCell struct {
+	// The text of the cell, usually a single character. The right half of a
+	// double-width character is the empty string.
+	text  string
+	style *gridStyle
+}
+
+// Substitutes the text of all cells with ' '
 func (n *NeoVim) ClearGrid() {
-	for i := range n.content.Rows {
-		for j := range n.content.Rows[i].Cells {
-			n.content.Rows[i].Cells[j].Rune = ' '
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
+	for i := range n.cells {
+		for j := range n.cells[i] {
+			n.cells[i][j].text = " "
 		}
 	}
+	n.markDirty(0, len(n.cells))
 }
 
-// Moves the displayed text up/down/lef/right
+// Moves the displayed text up/down/lef/right. The region is limited to the
+// grid, anything scrolled further than its height is dropped.
 func (n *NeoVim) ScrollGrid(top, bot, left, right, rows int) {
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
+	gridRows, gridCols := n.gridSize()
+	if top < 0 {
+		top = 0
+	}
+	if bot > gridRows {
+		bot = gridRows
+	}
+	if left < 0 {
+		left = 0
+	}
+	if right > gridCols {
+		right = gridCols
+	}
+	if top >= bot || left >= right {
+		return
+	}
+	if rows > bot-top {
+		rows = bot - top
+	} else if rows < top-bot {
+		rows = top - bot
+	}
+
+	n.recordScroll(top, bot, left, right, rows)
+
 	if rows > 0 {
 		// Scroll down
 		for row := top; row < bot-rows; row++ {
-			for col := left; col < right; col++ {
-				cell := n.content.Rows[row+rows].Cells[col]
-				n.content.Rows[row].Cells[col] = cell
-			}
+			copy(n.cells[row][left:right], n.cells[row+rows][left:right])
 		}
 	} else {
-		// Scroll up, start at bot-1 to skip the status line
-		for row := bot - 1; row > top+(-rows); row-- {
-			for col := left; col < right; col++ {
-				cell := n.content.Rows[row+rows].Cells[col]
-				n.content.Rows[row].Cells[col] = cell
-			}
+		// Scroll up
+		for row := bot - 1; row >= top-rows; row-- {
+			copy(n.cells[row][left:right], n.cells[row+rows][left:right])
 		}
 	}
+	n.markDirty(top, bot)
 }
 
-// Recovers the previous locations colors on horizontal movement and updates the
-// cursor position
+// Updates the cursor position. The cells are left as they are, the cursor is
+// drawn above them by the renderer.
 func (n *NeoVim) MoveGridCursor(oldRow, oldCol, newRow, newCol int) {
-	// recover the previous locations colors on horizontal movement
-	if oldRow == newRow {
-		r := n.content.Rows[oldRow].Cells[oldCol].Rune
-		cellStyle := &widget.CustomTextGridStyle{
-			FGColor: n.cursorCellFg,
-			BGColor: n.cursorCellBg,
-		}
-		recoveredCell := widget.TextGridCell{Rune: r, Style: cellStyle}
-		n.content.SetCell(oldRow, oldCol, recoveredCell)
-	}
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
 
-	newCell := n.content.Rows[newRow].Cells[newCol]
-	n.cursorCellFg = newCell.Style.TextColor()
-	n.cursorCellBg = newCell.Style.BackgroundColor()
 	n.cursorRow = newRow
 	n.cursorCol = newCol
 }
 
-// Writes a line of text (as defined by neovims ui events) to the textgrid
+// Writes a line of text (as defined by neovims ui events) to the grid
 func (n *NeoVim) WriteGridLine(row, col int, cells []interface{}) {
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
 	lastHL_id := 0
 	for _, cell := range cells {
 		cell := cell.([]interface{})
-		s := cell[0].(string)
-		r := rune(s[0])
+		text := cell[0].(string)
 
 		if len(cell) > 1 {
-			lastHL_id, _ = intOrUintToInt(cell[1])
+			lastHL_id = n.eventInt("grid_line", cell[1])
 		}
 
 		repeat := 1
 		if len(cell) > 2 {
-			repeat, _ = intOrUintToInt(cell[2])
+			repeat = n.eventInt("grid_line", cell[2])
 		}
 
 		for i := 0; i < repeat; i++ {
-			n.writeRune(row, col, r, lastHL_id)
-			if len(s) > 1 {
-				n.writeRune(row, col, ' ', lastHL_id)
-			}
+			n.writeCell(row, col, text, lastHL_id)
 			col++
 		}
 	}
+	n.markDirty(row, row+1)
 }
 
-// Changes the size of the textgrid, creating or removing rows and columns as
+// Changes the size of the grid, creating or removing rows and columns as
 // needed
 // TODO: One may consider only downsizing rows/cols if the difference is
 // significant
 func (n *NeoVim) ChangeVisualGridSize(targetRow, targetCol int) {
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
 	// remove rows
-	if targetRow < len(n.content.Rows) {
-		n.content.Rows = n.content.Rows[:targetRow]
+	if targetRow < len(n.cells) {
+		n.cells = n.cells[:targetRow]
+		n.dirty = n.dirty[:targetRow]
 	}
 
-	cellStyle := gridStyleFromHL(defaultHL)
+	cellStyle := n.styleFor(0)
 
 	for currRow := 0; currRow < targetRow; currRow++ {
 		// append new row if needed
-		if currRow > len(n.content.Rows)-1 {
-			n.content.Rows = append(n.content.Rows, widget.TextGridRow{})
+		if currRow > len(n.cells)-1 {
+			n.cells = append(n.cells, make([]gridCell, 0, targetCol))
+			n.dirty = append(n.dirty, true)
 		}
 
 		// remove columns
-		numCols := len(n.content.Rows[currRow].Cells)
-		if numCols > targetCol {
-			n.content.Rows[currRow].Cells =
-				n.content.Rows[currRow].Cells[:targetCol-1]
+		if len(n.cells[currRow]) > targetCol {
+			n.cells[currRow] = n.cells[currRow][:targetCol]
 		}
 
 		// append new columns if needed
-		for len(n.content.Rows[currRow].Cells) < targetCol {
-			newCell := widget.TextGridCell{
-				Rune:  ' ',
-				Style: cellStyle,
-			}
-			n.content.Rows[currRow].Cells =
-				append(n.content.Rows[currRow].Cells, newCell)
+		for len(n.cells[currRow]) < targetCol {
+			newCell := gridCell{text: " ", style: cellStyle}
+			n.cells[currRow] = append(n.cells[currRow], newCell)
 		}
 	}
+	n.markDirty(0, len(n.cells))
+}
+
+// Marks the rows from up to but excluding to as changed, so the renderer
+// redraws them on the next refresh. Has to be called with gridMu held.
+func (n *NeoVim) markDirty(from, to int) {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(n.dirty) {
+		to = len(n.dirty)
+	}
+	for row := from; row < to; row++ {
+		n.dirty[row] = true
+	}
+}
+
+// Writes the text of a cell to the grid, ignoring cells outside of it. Has to
+// be called with gridMu held.
+func (n *NeoVim) writeCell(row int, col int, text string, hl_id int) {
+	if row < 0 || row >= len(n.cells) || col < 0 || col >= len(n.cells[row]) {
+		return
+	}
+
+	n.cells[row][col] = gridCell{text: text, style: n.styleFor(hl_id)}
 }
 
-// Writes a rune to the textgrid
-func (n *NeoVim) writeRune(row int, col int, r rune, hl_id int) {
+// Returns the style for cells using the highlight hl_id. Styles are cached per
+// highlight and shared by all cells using it, so they must not be modified.
+func (n *NeoVim) styleFor(hl_id int) *gridStyle {
+	if style, ok := n.styles[hl_id]; ok {
+		return style
+	}
 
 	hl, ok := n.hl[hl_id]
 	if !ok {
-		hl = defaultHL
+		hl = n.defaultHL
 	}
 
-	cellStyle := gridStyleFromHL(hl)
-	n.content.SetCell(row, col, widget.TextGridCell{Rune: r, Style: cellStyle})
+	style := gridStyleFromHL(hl, n.defaultHL)
+	n.styles[hl_id] = style
+	return style
+}
+
+// The style of a cell, i.e. its highlight with the default colors resolved
+type gridStyle struct {
+	fg, bg color.Color
+	hl     highlight
+}
+
+// Returns the color of the text
+func (s *gridStyle) TextColor() color.Color {
+	return s.fg
 }
 
-func gridStyleFromHL(hl highlight) *widget.CustomTextGridStyle {
-	style := widget.CustomTextGridStyle{
-		FGColor: hl.Fg,
-		BGColor: hl.Bg,
+// Returns the color of the cell's background
+func (s *gridStyle) BackgroundColor() color.Color {
+	return s.bg
+}
+
+// Returns the style of a highlight, using the colors of defaults where it has
+// none
+func gridStyleFromHL(hl, defaults highlight) *gridStyle {
+	style := gridStyle{
+		fg: hl.Fg,
+		bg: hl.Bg,
+		hl: hl,
 	}
 
-	if style.FGColor == RGBA_SENTINEL {
-		style.FGColor = defaultHL.Fg
+	if style.fg == RGBA_SENTINEL {
+		style.fg = defaults.Fg
 	}
 
-	if style.BGColor == RGBA_SENTINEL {
-		style.BGColor = defaultHL.Bg
+	if style.bg == RGBA_SENTINEL {
+		style.bg = defaults.Bg
 	}
 
 	return &style
 }
+
+// Returns the content of the cell at row and col, ok is false if the cell is
+// outside of the grid
+func (n *NeoVim) CellAt(row, col int) (c Cell, ok bool) {
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
+	if row < 0 || row >= len(n.cells) || col < 0 || col >= len(n.cells[row]) {
+		return Cell{}, false
+	}
+
+	cell := n.cells[row][col]
+	c.Text = cell.text
+	if cell.style != nil {
+		c.Fg = cell.style.TextColor()
+		c.Bg = cell.style.BackgroundColor()
+	}
+	return c, true
+}
+
+// Returns the number of rows and columns of the grid
+func (n *NeoVim) GridSize() (rows, cols int) {
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
+	return n.gridSize()
+}
+
+// Returns the number of rows and columns of the grid, with gridMu held
+func (n *NeoVim) gridSize() (rows, cols int) {
+	rows = len(n.cells)
+	if rows > 0 {
+		cols = len(n.cells[0])
+	}
+	return rows, cols
+}
+
+// Returns the position of the cursor in the grid
+func (n *NeoVim) CursorPosition() (row, col int) {
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
+	return n.cursorRow, n.cursorCol
+}
@@ -0,0 +1,105 @@
+package nvim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/neovim/go-client/msgpack"
+)
+
+// Redraws are recorded as a stream of msgpack arrays, one per batch:
+// [unix time in nanoseconds, [event, ...]]
+// msgpack keeps the exact types nvim sent, so a replay goes through the same
+// code paths as the original session.
+
+// Writes redraw batches to the underlying writer, each one with a single write
+type redrawRecorder struct {
+	w   io.Writer
+	buf bytes.Buffer
+	enc *msgpack.Encoder
+}
+
+// Starts writing every redraw batch received from nvim to w, replacing any
+// previous recording. Passing nil stops recording. Recordings can be fed back
+// to a widget using ReplayRedraws.
+func (n *NeoVim) RecordRedraws(w io.Writer) {
+	n.recorderMu.Lock()
+	defer n.recorderMu.Unlock()
+
+	if w == nil {
+		n.recorder = nil
+		return
+	}
+	rec := &redrawRecorder{w: w}
+	rec.enc = msgpack.NewEncoder(&rec.buf)
+	n.recorder = rec
+}
+
+// Writes a batch to the recording, if there is one. Recording is stopped if
+// writing fails.
+func (n *NeoVim) recordRedraw(events [][]interface{}) {
+	n.recorderMu.Lock()
+	defer n.recorderMu.Unlock()
+
+	if n.recorder == nil {
+		return
+	}
+
+	rec := n.recorder
+	rec.buf.Reset()
+	err := rec.enc.Encode([]interface{}{time.Now().UnixNano(), events})
+	if err == nil {
+		_, err = rec.w.Write(rec.buf.Bytes())
+	}
+	if err != nil {
+		n.log().Error("recording redraw failed, stopped recording", "err", err)
+		n.recorder = nil
+	}
+}
+
+// Feeds redraw batches recorded with RecordRedraws to HandleNvimEvent. If
+// realtime is set the original delays between the batches are kept, otherwise
+// everything is replayed as fast as possible. Returns once the whole recording
+// was replayed.
+func (n *NeoVim) ReplayRedraws(r io.Reader, realtime bool) error {
+	dec := msgpack.NewDecoder(r)
+
+	var last int64
+	for {
+		var batch []interface{}
+		err := dec.Decode(&batch)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(batch) != 2 {
+			return fmt.Errorf("invalid redraw batch of length %d", len(batch))
+		}
+		timestamp, _ := intOrUintToInt(batch[0])
+		events, ok := batch[1].([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid redraw events %v", batch[1])
+		}
+
+		if realtime && last != 0 {
+			time.Sleep(time.Duration(int64(timestamp) - last))
+		}
+		last = int64(timestamp)
+
+		redraw := make([][]interface{}, len(events))
+		for i, e := range events {
+			event, ok := e.([]interface{})
+			if !ok {
+				return fmt.Errorf("invalid redraw event %v", e)
+			}
+			redraw[i] = event
+		}
+		n.handleRedraw(redraw)
+	}
+}
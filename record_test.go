@@ -0,0 +1,58 @@
+package nvim_test
+
+import (
+	"bytes"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+func TestRecordAndReplayRedraws(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	var recording bytes.Buffer
+	n.RecordRedraws(&recording)
+	peer.Redraw(
+		nvimtest.GridResize(20, 5),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"foreground": red}),
+		nvimtest.GridLine(0, 0, nvimtest.Cell("a", 1), nvimtest.Cell("b", 0)),
+	)
+	peer.Redraw(
+		nvimtest.GridLine(3, 0, nvimtest.Cell("x", 0, 4)),
+		nvimtest.GridCursorGoto(3, 15),
+		nvimtest.Flush(),
+	)
+	n.RecordRedraws(nil)
+	peer.Redraw(nvimtest.GridLine(4, 0, nvimtest.Cell("not recorded", 0)))
+
+	replayed := nvim.NewUnattached()
+	w := test.NewWindow(replayed)
+	defer w.Close()
+
+	err := replayed.ReplayRedraws(&recording, false)
+	assert.NoError(t, err)
+
+	rows, cols := replayed.GridSize()
+	assert.Equal(t, 5, rows)
+	assert.Equal(t, 20, cols)
+	assertText(t, replayed, 0, 0, "ab")
+	assertText(t, replayed, 3, 0, "xxxx")
+	assertText(t, replayed, 4, 0, " ")
+
+	c, _ := replayed.CellAt(0, 0)
+	assert.Equal(t, red, c.Fg)
+
+	row, col := replayed.CursorPosition()
+	assert.Equal(t, 3, row)
+	assert.Equal(t, 15, col)
+}
+
+func TestReplayRedrawsInvalid(t *testing.T) {
+	n := nvim.NewUnattached()
+
+	err := n.ReplayRedraws(bytes.NewReader([]byte{0x91, 0x01}), false)
+	assert.Error(t, err)
+}
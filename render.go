@@ -2,67 +2,515 @@ package nvim
 
 import (
 	"image/color"
+	"strings"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/widget"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"github.com/go-text/typesetting/harfbuzz"
 )
 
 // Declare conformity with the widget renderer interface
 var _ fyne.WidgetRenderer = (*render)(nil)
 
+// Draws the grid with as few canvas objects as possible. Neighbouring cells of
+// equal style share a text object, neighbouring backgrounds of equal color a
+// rectangle and cells in the default background are left to a single
+// rectangle behind everything. The objects of each row are kept and reused
+// between refreshes.
 type render struct {
 	*NeoVim
+
+	background *canvas.Rectangle // in the default background color
+	rows       []*rowObjects
+	changed    []fyne.CanvasObject // objects to refresh after updating the rows
+
+	// what the rows were last drawn with, all rows are redrawn if it changes
+	metrics  cellMetrics
+	textSize float32
+	scale    float32 // texts have to be rasterized again if it changes
+	origin   fyne.Position
+	padding  float32 // the grid is fitted again if it changes
+	shaping  bool    // whether runs are drawn as shapedText
+	features []harfbuzz.Feature
+	// the features as configured, parsed again if they change
+	featuresKey string
+	// the fallback font of texts the monospace font has no glyphs for, nil if
+	// there is none, forgotten when the fonts change
+	fallbacks      map[fallbackKey]fyne.Resource
+	fallbacksFonts []fyne.Resource
+
+	scroll    *scrollAnimation // set while a scroll is animated
+	cursor    *cursorOverlay
+	refreshMu sync.Mutex // guards the rows and scroll against the animation
+
+	mu      sync.Mutex
+	objects []fyne.CanvasObject // everything to draw, bottom to top
+}
+
+type fallbackKey struct {
+	text  string
+	style fyne.TextStyle
+}
+
+// The canvas objects drawing a single row. Only the first numBgs etc. objects
+// are in use, the others are kept for later.
+type rowObjects struct {
+	bgs    []*canvas.Rectangle
+	texts  []*canvas.Text
+	shaped []*shapedText  // instead of texts if Ligatures or FontFeatures are set
+	lines  []*canvas.Line // underlines and strikethroughs
+
+	numBgs, numTexts, numShaped, numLines int
+
+	moving objectRanges // the objects of the columns being scrolled
+	shift  float32      // how far they are moved down
+}
+
+// Ranges of the objects of a row, from the first to behind the last one
+type objectRanges struct {
+	bgs, texts, shaped, lines [2]int
+}
+
+func newRender(n *NeoVim) *render {
+	r := &render{
+		NeoVim:     n,
+		background: canvas.NewRectangle(n.defaultHL.Bg),
+		padding:    n.Padding,
+	}
+	r.objects = []fyne.CanvasObject{r.background}
+
+	return r
 }
 
 // Layout implements fyne.WidgetRenderer
 func (r *render) Layout(s fyne.Size) {
-	r.content.Resize(s)
+	r.background.Resize(s)
+	if o := r.debugOverlay(); o != nil {
+		o.layout(s)
+	}
 }
 
 // MinSize implements fyne.WidgetRenderer
 func (r *render) MinSize() fyne.Size {
-	cellSize := guessCellSize()
-	minWidth := cellSize.Width * MIN_COLS
-	minHeight := cellSize.Height * MIN_ROWS
-	return fyne.NewSize(minWidth, minHeight)
+	return r.cellMetrics().pixelSize(MIN_ROWS, MIN_COLS).
+		AddWidthHeight(2*r.Padding, 2*r.Padding)
 }
 
 // Refresh implements fyne.WidgetRenderer
 // The Refresh() method is triggered when the widget this renderer draws has
 // changed or if the theme is altered
+// Only the rows marked dirty are redrawn, unless the cells or colors changed.
 func (r *render) Refresh() {
-	r.refreshCursor()
-	r.content.Refresh()
+	start := time.Now()
+	r.refreshMu.Lock()
+	r.gridMu.Lock()
+	anims, refit := r.refresh()
+	r.gridMu.Unlock()
+	r.refreshMu.Unlock()
+
+	// nvim is asked without waiting for it, as its answer is redrawn
+	if refit {
+		go r.resizeGrid(r.availableSize())
+	}
+
+	// the test driver runs animations right away
+	for _, anim := range anims {
+		anim.Start()
+	}
+	r.debug.addRefresh(time.Since(start))
+}
+
+// Does the actual refresh, returning the scroll animations to start and whether
+// another number of cells fits now. Has to be called with refreshMu and gridMu
+// held.
+func (r *render) refresh() (anims []*fyne.Animation, refit bool) {
+	all := false
+	if bg := r.backgroundColor(); r.background.FillColor != bg {
+		r.background.FillColor = bg
+		r.changed = append(r.changed, r.background)
+		all = true
+	}
+	metrics, textSize, scale := r.cellMetrics(), theme.TextSize(), r.NeoVim.scale()
+	rescaled := scale != r.scale
+	// another number of cells fits into the widget, e.g. after the window
+	// moved to a monitor with another scale
+	refit = metrics != r.metrics && r.metrics != cellMetrics{}
+	if metrics != r.metrics || textSize != r.textSize || rescaled {
+		r.metrics, r.textSize, r.scale = metrics, textSize, scale
+		all = true
+	}
+	rows, cols := r.gridSize()
+	if origin := r.gridOrigin(metrics, rows, cols); origin != r.origin {
+		r.origin = origin
+		all = true
+	}
+	featuresKey := r.featuresKey
+	r.fontFeatures()
+	if shaping := r.shaped(); shaping != r.shaping || r.featuresKey != featuresKey {
+		r.shaping = shaping
+		all = true
+	}
+	if fonts := r.fallbackFonts(); !sameFonts(fonts, r.fallbacksFonts) {
+		r.fallbacks, r.fallbacksFonts = make(map[fallbackKey]fyne.Resource), fonts
+		all = true
+	}
+	if r.Padding != r.padding {
+		r.padding = r.Padding
+		refit = true
+	}
+
+	// new rows are always dirty
+	updated := len(r.rows) != len(r.cells)
+	if updated || all {
+		r.stopScroll()
+	}
+	for _, s := range r.takeScrolls() {
+		if anim := r.addScroll(s); anim != nil {
+			anims = append(anims, anim)
+		}
+	}
+	for len(r.rows) < len(r.cells) {
+		r.rows = append(r.rows, &rowObjects{})
+	}
+	r.rows = r.rows[:len(r.cells)]
+
+	for i, row := range r.cells {
+		if all || r.dirty[i] {
+			r.rows[i].update(r, row, i, metrics, r.origin)
+			r.dirty[i] = false
+			updated = true
+		}
+	}
+	if trail := r.CursorTrail && r.CursorAnimation > 0; r.cursor == nil || trail != (r.cursor.trail != nil) {
+		r.cursor = newCursorOverlay(trail)
+		updated = true
+	}
+	if anim := r.updateCursor(); anim != nil {
+		anims = append(anims, anim)
+	}
+	if r.scroll != nil {
+		updated = updated || r.scroll.linesChanged
+		r.updateScrollLines()
+		r.applyScroll()
+	}
+	if updated {
+		r.collectObjects()
+	}
+	// the driver keeps the texture of a text until it is refreshed, which would
+	// be scaled up or down from the old resolution otherwise
+	if rescaled {
+		for _, row := range r.rows {
+			for _, text := range row.texts[:row.numTexts] {
+				r.changed = append(r.changed, text)
+			}
+			for _, text := range row.shaped[:row.numShaped] {
+				r.changed = append(r.changed, text.Raster)
+			}
+		}
+	}
+
+	r.refreshChanged()
+
+	if o := r.debugOverlay(); o != nil {
+		o.layout(r.Size())
+	}
+	return anims, refit
 }
 
-// refreshCursor draws the cursor
-func (r *render) refreshCursor() {
-	cellStyle := &widget.CustomTextGridStyle{
-		FGColor: color.RGBA{200, 200, 200, 180},
-		BGColor: color.RGBA{255, 255, 255, 180},
+// Returns the color of the rectangle behind the cells, the default background
+// with the Transparency applied
+func (r *render) backgroundColor() color.Color {
+	switch {
+	case r.Transparency <= 0:
+		return r.defaultHL.Bg
+	case r.Transparency >= 1:
+		return color.Transparent
 	}
+	return withAlpha(r.defaultHL.Bg, 1-r.Transparency)
+}
 
-	currentRune := ' '
-	if r.cursorRow >= 0 && r.cursorRow < len(r.content.Rows) &&
-		r.cursorCol >= 0 && r.cursorCol < len(r.content.Rows[r.cursorRow].Cells) {
-		currentRune = r.content.Rows[r.cursorRow].Cells[r.cursorCol].Rune
+// Refreshes the objects which changed since the last call
+func (r *render) refreshChanged() {
+	for _, obj := range r.changed {
+		canvas.Refresh(obj)
 	}
+	r.changed = r.changed[:0]
+}
 
-	cursorCell := widget.TextGridCell{
-		Rune:  currentRune,
-		Style: cellStyle,
+// Collects the objects of all rows, backgrounds first so that no glyph is
+// covered by the background of a neighbouring cell
+func (r *render) collectObjects() {
+	rows := r.rows
+	if a := r.scroll; a != nil {
+		rows = append(append(rows[:len(rows):len(rows)], a.aboveRows...), a.belowRows...)
 	}
-	r.content.SetCell(r.cursorRow, r.cursorCol, cursorCell)
+
+	count := 1
+	for _, row := range rows {
+		count += row.numBgs + row.numTexts + row.numShaped + row.numLines
+	}
+
+	objects := make([]fyne.CanvasObject, 0, count+cursorTrailLength+3)
+	objects = append(objects, r.background)
+	for _, row := range rows {
+		for _, bg := range row.bgs[:row.numBgs] {
+			objects = append(objects, bg)
+		}
+	}
+	for _, row := range rows {
+		for _, text := range row.texts[:row.numTexts] {
+			objects = append(objects, text)
+		}
+		for _, text := range row.shaped[:row.numShaped] {
+			objects = append(objects, text.Raster)
+		}
+	}
+	for _, row := range rows {
+		for _, line := range row.lines[:row.numLines] {
+			objects = append(objects, line)
+		}
+	}
+	objects = append(objects, r.cursor.objects()...)
+
+	r.mu.Lock()
+	// objects which are no longer used have to disappear
+	if len(objects) != len(r.objects) {
+		r.changed = append(r.changed, r.background)
+	}
+	r.objects = objects
+	r.mu.Unlock()
 }
 
 // Objects implements fyne.WidgetRenderer
 func (r *render) Objects() []fyne.CanvasObject {
-	return []fyne.CanvasObject{r.content}
+	r.mu.Lock()
+	objects := r.objects
+	r.mu.Unlock()
+
+	if o := r.debugOverlay(); o != nil {
+		return append(objects[:len(objects):len(objects)], o.bg, o.label)
+	}
+	return objects
 }
 
 // Destroy implements fyne.WidgetRenderer
 // Is called when this renderer is no longer needed so it should clear any
 // resources that would otherwise leak
 func (r *render) Destroy() {
-	r.Engine.Close()
+	r.stopDebugOverlay()
+	if r.Engine != nil {
+		r.Engine.Close()
+	}
+}
+
+// Updates the objects of a row to draw cells at the given row index. While a
+// scroll is animated, the columns it moves get objects of their own.
+func (o *rowObjects) update(r *render, cells []gridCell, row int, m cellMetrics, origin fyne.Position) {
+	o.numBgs, o.numTexts, o.numShaped, o.numLines = 0, 0, 0, 0
+	o.moving, o.shift = objectRanges{}, 0
+
+	a := r.scroll
+	if a == nil || row < a.top || row >= a.bot {
+		o.draw(r, cells, row, m, origin, false)
+		return
+	}
+	left, right := clampCols(cells, a.left, a.right)
+	o.draw(r, cells[:left], row, m, origin, false)
+	o.draw(r, cells[left:right], row, m, origin.AddXY(float32(left)*m.Width, 0), true)
+	o.draw(r, cells[right:], row, m, origin.AddXY(float32(right)*m.Width, 0), false)
+}
+
+// Adds the objects to draw cells starting at origin to the row. If moving, they
+// are the ones moved by a scroll animation, of which each row has a single
+// range.
+func (o *rowObjects) draw(r *render, cells []gridCell, row int, m cellMetrics, origin fyne.Position, moving bool) {
+	if moving {
+		o.moving.bgs[0], o.moving.texts[0], o.moving.lines[0] = o.numBgs, o.numTexts, o.numLines
+		o.moving.shaped[0] = o.numShaped
+		defer func() {
+			o.moving.bgs[1], o.moving.texts[1], o.moving.lines[1] = o.numBgs, o.numTexts, o.numLines
+			o.moving.shaped[1] = o.numShaped
+		}()
+	}
+	y := origin.Y + float32(row)*m.Height
+
+	// backgrounds, merged while the color stays the same
+	for start := 0; start < len(cells); {
+		bg := r.cellStyle(cells[start]).bg
+		end := start + 1
+		for end < len(cells) && r.cellStyle(cells[end]).bg == bg {
+			end++
+		}
+
+		if bg != r.defaultHL.Bg {
+			o.addBg(r, bg, fyne.NewPos(origin.X+float32(start)*m.Width, y),
+				fyne.NewSize(float32(end-start)*m.Width, m.Height))
+		}
+		start = end
+	}
+
+	// text, merged while the style stays the same
+	for start := 0; start < len(cells); {
+		style := r.cellStyle(cells[start])
+		end := start + 1
+		if batchable(cells[start].text) {
+			for end < len(cells) && cells[end].style == cells[start].style &&
+				batchable(cells[end].text) {
+				end++
+			}
+		}
+		// the right halves of double-width characters
+		for end < len(cells) && cells[end].text == "" {
+			end++
+		}
+
+		var text strings.Builder
+		for _, c := range cells[start:end] {
+			text.WriteString(c.text)
+		}
+
+		pos := fyne.NewPos(origin.X+float32(start)*m.Width, y)
+		size := fyne.NewSize(float32(end-start)*m.Width, m.Height)
+		// trailing spaces only make the texture larger
+		if trimmed := strings.TrimRight(text.String(), " "); trimmed != "" {
+			var fallback fyne.Resource
+			if !batchable(cells[start].text) {
+				fallback = r.fallbackFont(trimmed, textStyleOf(style))
+			}
+			if fallback != nil || r.shaping && batchable(cells[start].text) {
+				o.addShaped(r, trimmed, style, pos, size, fallback)
+			} else {
+				o.addText(r, trimmed, style, pos, size)
+			}
+		}
+		o.addDecorations(r, style, m, pos, size.Width)
+
+		start = end
+	}
+}
+
+// Returns the style of a cell, which is the default one for cells which were
+// never written
+func (n *NeoVim) cellStyle(c gridCell) *gridStyle {
+	if c.style == nil {
+		return gridStyleFromHL(n.defaultHL, n.defaultHL)
+	}
+	return c.style
+}
+
+// Whether a cell can share a text object with its neighbours. That is the case
+// for ASCII, which the monospace font is guaranteed to have glyphs of the cell
+// width for.
+func batchable(text string) bool {
+	return len(text) == 1 && text[0] < 0x80
+}
+
+// Adds the lines for the underline and strikethrough styles. Underlines go
+// halfway into the descent, so they stay clear of the baseline but within the
+// cell, strikethroughs a third of the ascent above the baseline.
+func (o *rowObjects) addDecorations(r *render, s *gridStyle, m cellMetrics, pos fyne.Position, width float32) {
+	special := s.hl.Special
+	if special == RGBA_SENTINEL {
+		special = r.defaultHL.Special
+	}
+
+	baseline := pos.Y + m.Ascent
+	underline := baseline + m.Descent/2
+	if m.Descent < 2 {
+		underline = baseline + 1
+	}
+	switch {
+	case s.hl.Underdouble:
+		o.addLine(r, special, pos.X, underline, width)
+		o.addLine(r, special, pos.X, underline-2, width)
+	case s.hl.Underline, s.hl.Undercurl, s.hl.Underdotted, s.hl.Underdashed:
+		o.addLine(r, special, pos.X, underline, width)
+	}
+
+	if s.hl.Strikethrough {
+		o.addLine(r, s.fg, pos.X, baseline-m.Ascent/3, width)
+	}
+}
+
+func (o *rowObjects) addBg(r *render, c color.Color, pos fyne.Position, size fyne.Size) {
+	if o.numBgs == len(o.bgs) {
+		o.bgs = append(o.bgs, canvas.NewRectangle(c))
+	}
+	rect := o.bgs[o.numBgs]
+	o.numBgs++
+
+	if rect.FillColor != c || rect.Position() != pos || rect.Size() != size || !rect.Visible() {
+		rect.Show()
+		rect.FillColor = c
+		rect.Move(pos)
+		rect.Resize(size)
+		r.changed = append(r.changed, rect)
+	}
+}
+
+func (o *rowObjects) addText(r *render, s string, style *gridStyle, pos fyne.Position, size fyne.Size) {
+	if o.numTexts == len(o.texts) {
+		o.texts = append(o.texts, canvas.NewText("", style.fg))
+	}
+	text := o.texts[o.numTexts]
+	o.numTexts++
+
+	textStyle := textStyleOf(style)
+	textSize := r.textSize
+	if text.Text != s || text.Color != style.fg || text.TextStyle != textStyle ||
+		text.TextSize != textSize || text.Position() != pos || text.Size() != size || !text.Visible() {
+		text.Show()
+		text.Text = s
+		text.Color = style.fg
+		text.TextStyle = textStyle
+		text.TextSize = textSize
+		text.Move(pos)
+		text.Resize(size)
+		r.changed = append(r.changed, text)
+	}
+}
+
+// Returns the style of the texts drawing cells of the given style
+func textStyleOf(style *gridStyle) fyne.TextStyle {
+	return fyne.TextStyle{
+		Monospace: true,
+		Bold:      style.hl.Bold,
+		Italic:    style.hl.Italic,
+	}
+}
+
+func (o *rowObjects) addShaped(r *render, s string, style *gridStyle, pos fyne.Position, size fyne.Size, fallback fyne.Resource) {
+	if o.numShaped == len(o.shaped) {
+		o.shaped = append(o.shaped, newShapedText())
+	}
+	text := o.shaped[o.numShaped]
+	o.numShaped++
+
+	changed := text.set(r, s, style.fg, textStyleOf(style), fallback, size)
+	if changed || text.Position() != pos || !text.Visible() {
+		text.Show()
+		text.Move(pos)
+		r.changed = append(r.changed, text.Raster)
+	}
+}
+
+func (o *rowObjects) addLine(r *render, c color.Color, x, y, width float32) {
+	if o.numLines == len(o.lines) {
+		o.lines = append(o.lines, canvas.NewLine(c))
+	}
+	line := o.lines[o.numLines]
+	o.numLines++
+
+	pos1, pos2 := fyne.NewPos(x, y), fyne.NewPos(x+width, y)
+	if line.StrokeColor != c || line.Position1 != pos1 || line.Position2 != pos2 || !line.Visible() {
+		line.Show()
+		line.StrokeColor = c
+		line.StrokeWidth = 1
+		line.Position1 = pos1
+		line.Position2 = pos2
+		r.changed = append(r.changed, line)
+	}
 }
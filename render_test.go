@@ -0,0 +1,324 @@
+package nvim_test
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+// Returns the texts and rectangles the widget draws
+func drawnObjects(n *nvim.NeoVim) (texts []*canvas.Text, rects []*canvas.Rectangle) {
+	for _, obj := range test.WidgetRenderer(n).Objects() {
+		if !obj.Visible() {
+			continue
+		}
+		switch o := obj.(type) {
+		case *canvas.Text:
+			texts = append(texts, o)
+		case *canvas.Rectangle:
+			rects = append(rects, o)
+		}
+	}
+	return texts, rects
+}
+
+func TestRenderRuns(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.GridResize(20, 2),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"foreground": red, "bold": true}),
+		nvimtest.HLAttrDefine(2, map[string]interface{}{"background": green}),
+		nvimtest.GridLine(0, 0, append(nvimtest.Cells("func", 1), nvimtest.Cells(" main", 2)...)...),
+		nvimtest.GridLine(1, 0, nvimtest.Cell("字"), nvimtest.Cell(""), nvimtest.Cell("x")),
+		nvimtest.GridCursorGoto(1, 19),
+		nvimtest.Flush(),
+	)
+
+	texts, rects := drawnObjects(n)
+
+	var drawn []string
+	for _, text := range texts {
+		drawn = append(drawn, text.Text)
+	}
+	assert.Equal(t, []string{"func", " main", "字", "x"}, drawn)
+
+	cell := texts[0].Size().Width / 4
+	assert.True(t, texts[0].TextStyle.Bold)
+	assert.Equal(t, red, texts[0].Color)
+	assert.Equal(t, fyne.NewPos(cell*4, 0), texts[1].Position())
+
+	// the double-width character takes two cells
+	assert.Equal(t, texts[0].Size().Height, texts[2].Position().Y)
+	assert.Equal(t, cell*2, texts[2].Size().Width)
+	assert.Equal(t, cell*2, texts[3].Position().X)
+
+	// the default background, the merged green one and the cursor
+	if assert.Len(t, rects, 3) {
+		assert.Equal(t, black, rects[0].FillColor)
+		assert.Equal(t, green, rects[1].FillColor)
+		assert.Equal(t, fyne.NewPos(cell*4, 0), rects[1].Position())
+		assert.Equal(t, cell*5, rects[1].Size().Width)
+	}
+
+	c, _ := n.CellAt(1, 0)
+	assert.Equal(t, "字", c.Text)
+	c, _ = n.CellAt(1, 1)
+	assert.Equal(t, "", c.Text)
+}
+
+func TestRenderBlend(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.Transparency = 0.25
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.GridResize(20, 2),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"background": green, "blend": 40}),
+		nvimtest.HLAttrDefine(2, map[string]interface{}{"background": green}),
+		nvimtest.GridLine(0, 0, append(nvimtest.Cells("float", 1), nvimtest.Cells("text", 2)...)...),
+		nvimtest.GridCursorGoto(1, 19),
+		nvimtest.Flush(),
+	)
+
+	// the translucent default background, a single opaque one for both
+	// highlights as nvim already blended the float and the cursor
+	_, rects := drawnObjects(n)
+	if assert.Len(t, rects, 3) {
+		assert.Equal(t, color.NRGBA{A: 191}, rects[0].FillColor)
+		assert.Equal(t, green, rects[1].FillColor)
+		assert.Equal(t, 9*rects[2].Size().Width, rects[1].Size().Width)
+	}
+}
+
+// Returns the texts drawn in each row
+func drawnRows(n *nvim.NeoVim) map[float32][]string {
+	rows := make(map[float32][]string)
+	texts, _ := drawnObjects(n)
+	for _, text := range texts {
+		y := text.Position().Y
+		rows[y] = append(rows[y], text.Text)
+	}
+	return rows
+}
+
+func TestRenderDirtyRows(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.GridResize(10, 4),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("zero", 0)...),
+		nvimtest.GridLine(1, 0, nvimtest.Cells("one", 0)...),
+		nvimtest.GridLine(2, 0, nvimtest.Cells("two", 0)...),
+		nvimtest.GridCursorGoto(3, 9),
+		nvimtest.Flush(),
+	)
+	h := guessRowHeight(n)
+	assert.Equal(t, map[float32][]string{0: {"zero"}, h: {"one"}, 2 * h: {"two"}}, drawnRows(n))
+
+	// typing
+	peer.Redraw(
+		nvimtest.GridLine(1, 3, nvimtest.Cell("!")),
+		nvimtest.Flush(),
+	)
+	assert.Equal(t, map[float32][]string{0: {"zero"}, h: {"one!"}, 2 * h: {"two"}}, drawnRows(n))
+
+	// scrolling a line down
+	peer.Redraw(
+		nvimtest.GridScroll(0, 3, 0, 10, 1),
+		nvimtest.GridLine(2, 0, nvimtest.Cells("new ", 0)...),
+		nvimtest.Flush(),
+	)
+	assert.Equal(t, map[float32][]string{0: {"one!"}, h: {"two"}, 2 * h: {"new"}}, drawnRows(n))
+
+	// scrolling a line up
+	peer.Redraw(
+		nvimtest.GridScroll(0, 3, 0, 10, -1),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("top ", 0)...),
+		nvimtest.Flush(),
+	)
+	assert.Equal(t, map[float32][]string{0: {"top"}, h: {"one!"}, 2 * h: {"two"}}, drawnRows(n))
+
+	peer.Redraw(
+		nvimtest.GridClear(),
+		nvimtest.Flush(),
+	)
+	assert.Empty(t, drawnRows(n))
+}
+
+// Returns the height of a row, as the widget is sized to fit the grid exactly
+func guessRowHeight(n *nvim.NeoVim) float32 {
+	rows, _ := n.GridSize()
+	return n.Size().Height / float32(rows)
+}
+
+// Returns the size of a cell, which the block cursor covers
+func cellSize(n *nvim.NeoVim) fyne.Size {
+	_, rects := drawnObjects(n)
+	return rects[len(rects)-1].Size()
+}
+
+// Returns the columns and rows of the last grid size requested from nvim
+func lastTryResize(t *testing.T, peer *nvimtest.Peer) (cols, rows int) {
+	t.Helper()
+
+	args, ok := peer.LastCall("nvim_ui_try_resize_grid")
+	if !assert.True(t, ok, "no resize requested") {
+		t.FailNow()
+	}
+	return int(args[1].(int64)), int(args[2].(int64))
+}
+
+func TestRenderRescale(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	c := fyne.CurrentApp().Driver().CanvasForObject(n).(test.WindowlessCanvas)
+
+	size := fyne.NewSize(403, 211)
+	c.Resize(size)
+	cols, rows := lastTryResize(t, peer)
+	peer.Redraw(
+		nvimtest.GridResize(cols, rows),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("text", 0)...),
+		nvimtest.Flush(),
+	)
+	// the widget still fills the window, even if the cells don't
+	assert.Equal(t, size, n.Size())
+
+	// the next redraw after the scale changed fits the grid again
+	requested := len(peer.Calls("nvim_ui_try_resize_grid"))
+	c.SetScale(1.25)
+	peer.Redraw(nvimtest.Flush())
+	assert.Eventually(t, func() bool {
+		return len(peer.Calls("nvim_ui_try_resize_grid")) == requested+1
+	}, time.Second, time.Millisecond)
+}
+
+func TestRenderPadding(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	c := fyne.CurrentApp().Driver().CanvasForObject(n).(test.WindowlessCanvas)
+
+	n.Padding = 10
+	size := fyne.NewSize(403, 211)
+	c.Resize(size)
+	cols, rows := lastTryResize(t, peer)
+	peer.Redraw(
+		nvimtest.GridResize(cols, rows),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("text", 0)...),
+		nvimtest.GridCursorGoto(rows-1, cols-1),
+		nvimtest.Flush(),
+	)
+	assert.Equal(t, size, n.Size())
+
+	// the first row is a single run
+	texts, _ := drawnObjects(n)
+	cell := texts[0].Size()
+	cell.Width /= float32(cols)
+	assert.Equal(t, fyne.NewPos(10, 10), texts[0].Position())
+	// the grid fits between the padding, but another cell wouldn't
+	assert.LessOrEqual(t, float32(cols)*cell.Width, size.Width-20)
+	assert.Greater(t, float32(cols+1)*cell.Width, size.Width-20)
+	assert.LessOrEqual(t, float32(rows)*cell.Height, size.Height-20)
+	assert.Greater(t, float32(rows+1)*cell.Height, size.Height-20)
+
+	n.CenterGrid = true
+	n.Refresh()
+	texts, _ = drawnObjects(n)
+	free := size.Subtract(fyne.NewSize(float32(cols)*cell.Width, float32(rows)*cell.Height))
+	assert.InDelta(t, free.Width/2, texts[0].Position().X, 0.5)
+	assert.InDelta(t, free.Height/2, texts[0].Position().Y, 0.5)
+
+	// more padding fits fewer cells, asked for by the refresh itself
+	n.Padding = 40
+	n.Refresh()
+	assert.Eventually(t, func() bool {
+		args, _ := peer.LastCall("nvim_ui_try_resize_grid")
+		return int(args[1].(int64)) < cols
+	}, time.Second, time.Millisecond)
+}
+
+func TestRenderCursor(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.GridResize(10, 2),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"foreground": red}),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("ab", 1)...),
+		nvimtest.GridCursorGoto(0, 1),
+		nvimtest.Flush(),
+	)
+	cursor := func() (*canvas.Rectangle, *canvas.Text) {
+		texts, rects := drawnObjects(n)
+		return rects[len(rects)-1], texts[len(texts)-1]
+	}
+
+	// without the focus it only outlines the cell
+	rect, _ := cursor()
+	assert.Equal(t, color.Transparent, rect.FillColor)
+	assert.Equal(t, red, rect.StrokeColor)
+
+	// a block inverts the colors of its cell
+	fyne.CurrentApp().Driver().CanvasForObject(n).Focus(n)
+	rect, text := cursor()
+	assert.Equal(t, red, rect.FillColor)
+	assert.Equal(t, "b", text.Text)
+	assert.Equal(t, black, text.Color)
+	assert.Equal(t, rect.Position(), text.Position())
+	cell := rect.Size()
+
+	peer.Redraw(
+		nvimtest.ModeInfoSet(
+			map[string]interface{}{"name": "insert", "cursor_shape": "vertical", "cell_percentage": 25},
+		),
+		nvimtest.ModeChange("insert", 0),
+		nvimtest.GridCursorGoto(1, 0),
+		nvimtest.Flush(),
+	)
+	rect, text = cursor()
+	assert.Equal(t, fyne.NewSize(cell.Width/4, cell.Height), rect.Size())
+	assert.Equal(t, fyne.NewPos(0, cell.Height), rect.Position())
+	assert.Equal(t, "ab", text.Text)
+
+	// the cells are never changed by the cursor
+	c, _ := n.CellAt(0, 1)
+	assert.Equal(t, red, c.Fg)
+}
+
+func TestRenderUnfocusedCursor(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.NotifyFocus = true
+
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.GridResize(10, 2),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("ab", 0)...),
+		nvimtest.Flush(),
+	)
+	c := fyne.CurrentApp().Driver().CanvasForObject(n)
+	c.Focus(n)
+	_, focused := drawnObjects(n)
+
+	// the cell shines through a dimmed cursor
+	n.UnfocusedCursor = nvim.CursorDimmed
+	c.Unfocus()
+	_, rects := drawnObjects(n)
+	_, _, _, a := rects[len(rects)-1].FillColor.RGBA()
+	assert.Less(t, a, uint32(0xffff))
+	assert.Greater(t, a, uint32(0))
+
+	n.UnfocusedCursor = nvim.CursorHidden
+	n.Refresh()
+	_, rects = drawnObjects(n)
+	assert.Len(t, rects, len(focused)-1)
+
+	// nvim is told about both
+	assert.Equal(t, [][]interface{}{{true}, {false}}, peer.Calls("nvim_ui_set_focus"))
+}
@@ -0,0 +1,256 @@
+package nvim
+
+import (
+	"math"
+
+	"fyne.io/fyne/v2"
+)
+
+// A grid_scroll waiting to be animated by the next refresh
+type pendingScroll struct {
+	top, bot, left, right, rows int
+
+	// the lines which left the region, the one closest to it first
+	lines [][]gridCell
+}
+
+// Remembers a scroll of the region for the renderer to animate, along with
+// the lines about to be scrolled out of it. Has to be called before the cells
+// are moved.
+func (n *NeoVim) recordScroll(top, bot, left, right, rows int) {
+	if n.ScrollAnimation <= 0 || rows == 0 {
+		return
+	}
+
+	s := pendingScroll{top: top, bot: bot, left: left, right: right, rows: rows}
+	if rows > 0 {
+		for row := top + rows - 1; row >= top; row-- {
+			s.lines = append(s.lines, append([]gridCell(nil), n.cells[row]...))
+		}
+	} else {
+		for row := bot + rows; row < bot; row++ {
+			s.lines = append(s.lines, append([]gridCell(nil), n.cells[row]...))
+		}
+	}
+
+	n.scrollMu.Lock()
+	n.scrolls = append(n.scrolls, s)
+	n.scrollMu.Unlock()
+}
+
+// Returns the scrolls recorded since the last call
+func (n *NeoVim) takeScrolls() []pendingScroll {
+	n.scrollMu.Lock()
+	defer n.scrollMu.Unlock()
+
+	scrolls := n.scrolls
+	n.scrolls = nil
+	return scrolls
+}
+
+// A scroll being animated. The cells of the region are drawn offset rows
+// below where they are, which eases to zero. The lines which left the region
+// are drawn next to it until they are scrolled out of view.
+type scrollAnimation struct {
+	top, bot, left, right int
+
+	offset float32 // in rows, negative if drawn above
+	anim   *fyne.Animation
+
+	above, below         [][]gridCell // closest to the region first
+	aboveRows, belowRows []*rowObjects
+	linesChanged         bool // the rows of above and below need an update
+}
+
+// Adds a scroll to the running animation or starts a new one. Returns the
+// animation to start once the refresh is done.
+func (r *render) addScroll(s pendingScroll) *fyne.Animation {
+	a := r.scroll
+	if a != nil && (a.top != s.top || a.bot != s.bot || a.left != s.left || a.right != s.right) {
+		r.stopScroll()
+		a = nil
+	}
+	if a == nil {
+		a = &scrollAnimation{top: s.top, bot: s.bot, left: s.left, right: s.right}
+		r.scroll = a
+	}
+
+	// lines on the other side are covered by the cells moving in
+	if s.rows > 0 {
+		a.above = append(s.lines, a.above...)
+		a.below = dropLines(a.below, s.rows)
+	} else {
+		a.below = append(s.lines, a.below...)
+		a.above = dropLines(a.above, -s.rows)
+	}
+	a.offset += float32(s.rows)
+	a.above = keepLines(a.above, a.offset)
+	a.below = keepLines(a.below, -a.offset)
+	a.linesChanged = true
+
+	if a.anim != nil {
+		a.anim.Stop()
+	}
+	from := a.offset
+	var anim *fyne.Animation
+	anim = fyne.NewAnimation(r.ScrollAnimation, func(progress float32) {
+		r.tickScroll(a, anim, from, progress)
+	})
+	anim.Curve = fyne.AnimationEaseOut
+	a.anim = anim
+
+	return anim
+}
+
+// Drops the first n lines, i.e. the ones closest to the region
+func dropLines(lines [][]gridCell, n int) [][]gridCell {
+	if n >= len(lines) {
+		return nil
+	}
+	return lines[n:]
+}
+
+// Keeps as many lines as it takes to fill a gap of the given rows
+func keepLines(lines [][]gridCell, rows float32) [][]gridCell {
+	n := int(math.Ceil(float64(rows)))
+	if n <= 0 {
+		return nil
+	}
+	if n < len(lines) {
+		return lines[:n]
+	}
+	return lines
+}
+
+// Moves the region closer to where its cells are, called for every frame of
+// the animation
+func (r *render) tickScroll(a *scrollAnimation, anim *fyne.Animation, from, progress float32) {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
+	// replaced by another scroll meanwhile
+	if r.scroll != a || a.anim != anim {
+		return
+	}
+
+	if progress >= 1 {
+		r.stopScroll()
+		r.collectObjects()
+	} else {
+		a.offset = from * (1 - progress)
+		r.applyScroll()
+	}
+	r.refreshChanged()
+}
+
+// Ends the animation right away, drawing the region where its cells are
+func (r *render) stopScroll() {
+	a := r.scroll
+	if a == nil {
+		return
+	}
+
+	a.anim.Stop()
+	a.offset = 0
+	a.aboveRows, a.belowRows = nil, nil
+	r.applyScroll()
+	r.scroll = nil
+}
+
+// Updates the objects of the lines next to the region, if they changed
+func (r *render) updateScrollLines() {
+	a := r.scroll
+	if a == nil || !a.linesChanged {
+		return
+	}
+	a.linesChanged = false
+
+	lines := func(lines [][]gridCell, row func(int) int) []*rowObjects {
+		rows := make([]*rowObjects, len(lines))
+		for i, line := range lines {
+			left, right := clampCols(line, a.left, a.right)
+			origin := r.origin.AddXY(float32(left)*r.metrics.Width, 0)
+
+			rows[i] = &rowObjects{}
+			rows[i].draw(r, line[left:right], row(i), r.metrics, origin, true)
+		}
+		return rows
+	}
+	a.aboveRows = lines(a.above, func(i int) int { return a.top - 1 - i })
+	a.belowRows = lines(a.below, func(i int) int { return a.bot + i })
+}
+
+// Returns left and right limited to the columns of cells
+func clampCols(cells []gridCell, left, right int) (int, int) {
+	if right > len(cells) {
+		right = len(cells)
+	}
+	if left > right {
+		left = right
+	}
+	return left, right
+}
+
+// Moves the objects of the region and of the lines next to it by the offset
+// of the animation. Rows which are not entirely within the region are hidden,
+// as they would cover the cells around it otherwise.
+func (r *render) applyScroll() {
+	a := r.scroll
+	h := r.metrics.Height
+	minY := r.origin.Y + float32(a.top)*h
+	maxY := r.origin.Y + float32(a.bot)*h
+	shift := a.offset * h
+
+	for row := a.top; row < a.bot && row < len(r.rows); row++ {
+		r.rows[row].shiftTo(r, row, shift, minY, maxY)
+	}
+	for i, o := range a.aboveRows {
+		o.shiftTo(r, a.top-1-i, shift, minY, maxY)
+	}
+	for i, o := range a.belowRows {
+		o.shiftTo(r, a.bot+i, shift, minY, maxY)
+	}
+}
+
+// Moves the objects of the scrolled columns of the row to be shift below their
+// cells, showing them only if they are between minY and maxY
+func (o *rowObjects) shiftTo(r *render, row int, shift, minY, maxY float32) {
+	// a hair of tolerance for the float arithmetic
+	const epsilon = 1e-3
+
+	y := r.origin.Y + float32(row)*r.metrics.Height + shift
+	visible := y >= minY-epsilon && y+r.metrics.Height <= maxY+epsilon
+	delta := shift - o.shift
+	o.shift = shift
+
+	for _, obj := range o.movingObjects() {
+		if delta == 0 && obj.Visible() == visible {
+			continue
+		}
+		obj.Move(obj.Position().AddXY(0, delta))
+		if visible {
+			obj.Show()
+		} else {
+			obj.Hide()
+		}
+		r.changed = append(r.changed, obj)
+	}
+}
+
+// Returns the objects of the columns being scrolled
+func (o *rowObjects) movingObjects() []fyne.CanvasObject {
+	var objects []fyne.CanvasObject
+	for _, bg := range o.bgs[o.moving.bgs[0]:o.moving.bgs[1]] {
+		objects = append(objects, bg)
+	}
+	for _, text := range o.texts[o.moving.texts[0]:o.moving.texts[1]] {
+		objects = append(objects, text)
+	}
+	for _, text := range o.shaped[o.moving.shaped[0]:o.moving.shaped[1]] {
+		objects = append(objects, text.Raster)
+	}
+	for _, line := range o.lines[o.moving.lines[0]:o.moving.lines[1]] {
+		objects = append(objects, line)
+	}
+	return objects
+}
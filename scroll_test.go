@@ -0,0 +1,81 @@
+package nvim_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+// Returns the texts drawn in each row, in rows of the grid
+func drawnGridRows(n *nvim.NeoVim) map[float32][]string {
+	h := guessRowHeight(n)
+	rows := make(map[float32][]string)
+	for y, texts := range drawnRows(n) {
+		rows[y/h] = texts
+	}
+	return rows
+}
+
+// Redraws a grid of four lines, the last one being a status line
+func redrawLines(peer *nvimtest.Peer) {
+	peer.Redraw(
+		nvimtest.GridResize(10, 4),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("zero", 0)...),
+		nvimtest.GridLine(1, 0, nvimtest.Cells("one", 0)...),
+		nvimtest.GridLine(2, 0, nvimtest.Cells("two", 0)...),
+		nvimtest.GridLine(3, 0, nvimtest.Cells("status", 0)...),
+		nvimtest.GridCursorGoto(3, 9),
+		nvimtest.Flush(),
+	)
+}
+
+func TestScrollAnimation(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.ScrollAnimation = time.Second
+	anims := nvimtest.HoldAnimations()
+	redrawLines(peer)
+
+	// scrolling the lines above the status line down by one
+	peer.Redraw(
+		nvimtest.GridScroll(0, 3, 0, 10, 1),
+		nvimtest.GridLine(2, 0, nvimtest.Cells("new ", 0)...),
+		nvimtest.Flush(),
+	)
+	assert.Equal(t, 1, anims.Running())
+
+	// first drawn where the lines were, hiding the new one below the region
+	assert.Equal(t, map[float32][]string{
+		0: {"zero"}, 1: {"one"}, 2: {"two"}, 3: {"status"},
+	}, drawnGridRows(n))
+
+	// halfway, which is three quarters along the ease out, rows only partially
+	// within the region are hidden
+	anims.Tick(0.5)
+	assert.Equal(t, map[float32][]string{
+		0.25: {"one"}, 1.25: {"two"}, 3: {"status"},
+	}, drawnGridRows(n))
+
+	anims.Tick(1)
+	assert.Equal(t, map[float32][]string{
+		0: {"one"}, 1: {"two"}, 2: {"new"}, 3: {"status"},
+	}, drawnGridRows(n))
+}
+
+func TestScrollAnimationDisabled(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	anims := nvimtest.HoldAnimations()
+	redrawLines(peer)
+
+	peer.Redraw(
+		nvimtest.GridScroll(0, 3, 0, 10, 1),
+		nvimtest.GridLine(2, 0, nvimtest.Cells("new ", 0)...),
+		nvimtest.Flush(),
+	)
+	assert.Zero(t, anims.Running())
+	assert.Equal(t, map[float32][]string{
+		0: {"one"}, 1: {"two"}, 2: {"new"}, 3: {"status"},
+	}, drawnGridRows(n))
+}
@@ -0,0 +1,421 @@
+package nvim
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	textrender "github.com/go-text/render"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/harfbuzz"
+	"github.com/go-text/typesetting/opentype/api"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// The features implementing ligatures, which are turned off unless Ligatures
+// is set
+var ligatureFeatures = []string{"-liga", "-clig", "-calt"}
+
+// The fonts parsed for shaping and the buffer to shape in, which are not safe
+// for concurrent use
+var shaper struct {
+	sync.Mutex
+	buf   *harfbuzz.Buffer
+	fonts map[fyne.Resource]*shaperFont // by the resource, names need not be unique
+}
+
+type shaperFont struct {
+	face font.Face
+	hb   *harfbuzz.Font
+}
+
+// Whether runs of cells are shaped with the configured features instead of
+// being drawn as fyne texts
+func (n *NeoVim) shaped() bool {
+	return n.Ligatures || len(n.FontFeatures) > 0
+}
+
+// Returns the OpenType features to shape with, parsing them again if the
+// options changed. Features which don't parse are logged once and left out.
+func (r *render) fontFeatures() []harfbuzz.Feature {
+	key := strings.Join(r.FontFeatures, ",")
+	if !r.Ligatures {
+		key = strings.Join(append(ligatureFeatures[:len(ligatureFeatures):len(ligatureFeatures)], r.FontFeatures...), ",")
+	}
+	if key == r.featuresKey {
+		return r.features
+	}
+	r.featuresKey = key
+
+	// the old ones may still be drawn with
+	r.features = nil
+	for _, s := range strings.Split(key, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		feature, err := harfbuzz.ParseFeature(s)
+		if err != nil {
+			r.log().Warn("invalid font feature", "feature", s, "err", err)
+			continue
+		}
+		r.features = append(r.features, feature)
+	}
+	return r.features
+}
+
+// Returns the font emoji are drawn in, the one bundled with fyne by default
+func (n *NeoVim) emojiFont() fyne.Resource {
+	if n.EmojiFont != nil {
+		return n.EmojiFont
+	}
+	return theme.DefaultEmojiFont()
+}
+
+// Whether text is an emoji, i.e. has a character shown as emoji by default,
+// including the regional indicators of flags, or the selector asking for emoji
+// presentation
+func isEmoji(text string) bool {
+	for _, r := range text {
+		if unicode.Is(emojiPresentationTable, r) || r == emojiPresentation || r == zeroWidthJoiner || r == combiningKeycap {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	zeroWidthJoiner   = '\u200d'
+	combiningKeycap   = '\u20e3'
+	emojiPresentation = '\ufe0f'
+)
+
+// Returns what the fallback fonts depend on, the monospace font of the theme,
+// the emoji font and the FallbackFonts
+func (r *render) fallbackFonts() []fyne.Resource {
+	return append([]fyne.Resource{theme.TextMonospaceFont(), r.emojiFont()}, r.FallbackFonts...)
+}
+
+// Whether both are the same fonts in the same order
+func sameFonts(a, b []fyne.Resource) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the font to draw text in if the monospace font of the theme has no
+// glyphs for it, which is the first of the FallbackFonts that has. Emoji are
+// drawn in the emoji font if it has them, even if the monospace font has a
+// glyph as well. Returns nil if the monospace font will do, or if none of the
+// fonts has the glyphs either.
+func (r *render) fallbackFont(text string, style fyne.TextStyle) fyne.Resource {
+	key := fallbackKey{text: text, style: style}
+	if res, ok := r.fallbacks[key]; ok {
+		return res
+	}
+
+	var fallback fyne.Resource
+	primary := fyne.CurrentApp().Settings().Theme().Font(style)
+	if isEmoji(text) {
+		ok, err := hasGlyphs(r.emojiFont(), text)
+		if err != nil {
+			r.log().Error("loading emoji font failed", "err", err)
+		}
+		if ok {
+			fallback = r.emojiFont()
+		}
+	}
+	if ok, _ := hasGlyphs(primary, text); fallback == nil && !ok {
+		for _, res := range r.FallbackFonts {
+			ok, err := hasGlyphs(res, text)
+			if err != nil {
+				r.log().Error("loading fallback font failed", "err", err)
+			}
+			if ok {
+				fallback = res
+				break
+			}
+		}
+	}
+	r.fallbacks[key] = fallback
+	return fallback
+}
+
+// A run of cells drawn by shaping its text with HarfBuzz, which canvas.Text
+// doesn't allow to set features or fonts for. Every cluster is put at the
+// start of its cell, so ligatures stay on the grid even if the advances of the
+// font don't add up to whole cells. A cell drawn in a fallback font is scaled
+// down to fit its cells instead, or centered if it is narrower, keeping it on
+// the baseline of the text. The drivers
+// only know the Raster, which is what the renderer returns as object.
+type shapedText struct {
+	*canvas.Raster
+
+	fallback  fyne.Resource // the font to draw in instead of the theme's
+	text      string
+	color     color.Color
+	textStyle fyne.TextStyle
+	textSize  float32
+	metrics   cellMetrics
+	features  []harfbuzz.Feature
+	logger    Logger
+}
+
+func newShapedText() *shapedText {
+	s := &shapedText{}
+	s.Raster = canvas.NewRaster(s.draw)
+	return s
+}
+
+// Sets what to draw, returning whether it changed. The features are those of
+// the renderer, which only change along with everything else.
+func (s *shapedText) set(r *render, text string, fg color.Color, textStyle fyne.TextStyle, fallback fyne.Resource, size fyne.Size) bool {
+	// fallback fonts are shaped as they come
+	features := r.features
+	if fallback != nil {
+		features = nil
+	}
+	if s.fallback == fallback && s.text == text && s.color == fg && s.textStyle == textStyle &&
+		s.textSize == r.textSize && s.metrics == r.metrics && s.Size() == size {
+		return false
+	}
+
+	s.fallback = fallback
+	s.text = text
+	s.color = fg
+	s.textStyle = textStyle
+	s.textSize = r.textSize
+	s.metrics = r.metrics
+	s.features = features
+	s.logger = r.log()
+	s.Resize(size)
+	return true
+}
+
+// Draws the glyphs into an image of w by h pixels
+func (s *shapedText) draw(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == 0 || s.Size().Width == 0 || s.text == "" {
+		return img
+	}
+	scale := float32(w) / s.Size().Width
+
+	res, fontSize, x := s.fallback, s.textSize, float32(0)
+	if res == nil {
+		res = fyne.CurrentApp().Settings().Theme().Font(s.textStyle)
+	}
+
+	// the faces are used for drawing as well
+	shaper.Lock()
+	defer shaper.Unlock()
+
+	run, err := shapeText(res, s.text, s.textSize, s.features)
+	if err != nil {
+		s.logger.Error("shaping text failed", "err", err)
+	}
+	if len(run.Glyphs) == 0 {
+		return img
+	}
+	if s.fallback != nil {
+		var fit float32
+		fit, x = fitRun(&run, s.Size().Width, s.metrics.Ascent, s.metrics.Descent)
+		fontSize *= fit
+	} else {
+		snapToCells(&run, s.metrics.Width)
+	}
+	placeBitmaps(&run, fontSize*scale)
+
+	r := textrender.Renderer{FontSize: fontSize, PixScale: scale, Color: s.color}
+	r.DrawShapedRunAt(run, img, int(math.Round(float64(x*scale))),
+		int(math.Round(float64(s.metrics.Ascent*scale))))
+	return img
+}
+
+// Returns the parsed font, nil if it failed to parse before. Has to be called
+// with the shaper locked.
+func parsedFont(res fyne.Resource) (*shaperFont, error) {
+	f, parsed := shaper.fonts[res]
+	if parsed {
+		return f, nil
+	}
+
+	if shaper.fonts == nil {
+		shaper.fonts = make(map[fyne.Resource]*shaperFont)
+	}
+	face, err := font.ParseTTF(bytes.NewReader(res.Content()))
+	if err != nil {
+		shaper.fonts[res] = nil
+		return nil, fmt.Errorf("parsing font %s: %w", res.Name(), err)
+	}
+	f = &shaperFont{face: face, hb: harfbuzz.NewFont(face)}
+	shaper.fonts[res] = f
+	return f, nil
+}
+
+// Whether the font has glyphs for all runes of text, apart from the ones which
+// are never drawn like variation selectors. Fonts which fail to parse have
+// none.
+func hasGlyphs(res fyne.Resource, text string) (bool, error) {
+	shaper.Lock()
+	defer shaper.Unlock()
+
+	f, err := parsedFont(res)
+	if f == nil {
+		return false, err
+	}
+	for _, r := range text {
+		if r == zeroWidthJoiner || unicode.Is(unicode.Variation_Selector, r) {
+			continue
+		}
+		if _, ok := f.face.NominalGlyph(r); !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Shapes text with the given features, laid out as the font says. Fonts which
+// fail to parse return an error once and no glyphs from then on. Has to be
+// called with the shaper locked.
+func shapeText(res fyne.Resource, text string, size float32, features []harfbuzz.Feature) (shaping.Output, error) {
+	f, err := parsedFont(res)
+	if f == nil {
+		return shaping.Output{}, err
+	}
+	if shaper.buf == nil {
+		shaper.buf = harfbuzz.NewBuffer()
+	}
+
+	// scaled to 26.6 fixed point values of the text size
+	f.hb.XScale = int32(math.Round(float64(size) * (1 << 6)))
+	f.hb.YScale = f.hb.XScale
+
+	runes := []rune(text)
+	buf := shaper.buf
+	buf.Clear()
+	buf.AddRunes(runes, 0, len(runes))
+	buf.GuessSegmentProperties()
+	buf.Props.Direction = harfbuzz.LeftToRight
+	buf.Shape(f.hb, features)
+
+	run := shaping.Output{
+		Glyphs: make([]shaping.Glyph, len(buf.Info)),
+		Face:   f.face,
+		Size:   fixed.Int26_6(f.hb.XScale),
+	}
+	for i, info := range buf.Info {
+		g := &run.Glyphs[i]
+		*g = shaping.Glyph{
+			ClusterIndex: info.Cluster,
+			GlyphID:      info.Glyph,
+			XAdvance:     fixed.Int26_6(buf.Pos[i].XAdvance),
+			XOffset:      fixed.Int26_6(buf.Pos[i].XOffset),
+			YOffset:      fixed.Int26_6(buf.Pos[i].YOffset),
+		}
+		// bitmap and SVG glyphs are drawn into their extents
+		if extents, ok := f.hb.GlyphExtents(info.Glyph); ok {
+			g.Width = fixed.Int26_6(extents.Width)
+			g.Height = fixed.Int26_6(extents.Height)
+			g.XBearing = fixed.Int26_6(extents.XBearing)
+			g.YBearing = fixed.Int26_6(extents.YBearing)
+		}
+	}
+	return run, nil
+}
+
+// Moves each glyph of a run, which takes a cell for every rune, to the cell of
+// the first rune it was shaped from
+func snapToCells(run *shaping.Output, cellWidth float32) {
+	// where each glyph starts, within its cluster as the font says
+	pens := make([]float32, len(run.Glyphs)+1)
+	cluster, pen := -1, float32(0)
+	for i, g := range run.Glyphs {
+		if g.ClusterIndex != cluster {
+			cluster = g.ClusterIndex
+			pen = float32(cluster) * cellWidth
+		}
+		pens[i] = pen
+		pen += fixed26ToFloat32(g.XAdvance)
+	}
+	pens[len(run.Glyphs)] = pen
+	for i := range run.Glyphs {
+		run.Glyphs[i].XAdvance = float32ToFixed26(pens[i+1] - pens[i])
+	}
+}
+
+// Scales a run down to fit into width and between ascent above and descent
+// below the baseline. Returns the factor it was scaled by and where it starts
+// to be centered within width.
+func fitRun(run *shaping.Output, width, ascent, descent float32) (scale, x float32) {
+	advance, top, bottom := float32(0), float32(0), float32(0)
+	for _, g := range run.Glyphs {
+		advance += fixed26ToFloat32(g.XAdvance)
+		// the extents go up from the baseline
+		if y := fixed26ToFloat32(g.YOffset + g.YBearing); y > top {
+			top = y
+		}
+		if y := -fixed26ToFloat32(g.YOffset + g.YBearing + g.Height); y > bottom {
+			bottom = y
+		}
+	}
+
+	scale = 1
+	if advance > width {
+		scale = width / advance
+	}
+	if top*scale > ascent {
+		scale = ascent / top
+	}
+	if bottom*scale > descent {
+		scale = descent / bottom
+	}
+	if scale < 1 {
+		for i := range run.Glyphs {
+			g := &run.Glyphs[i]
+			for _, v := range []*fixed.Int26_6{&g.XAdvance, &g.XOffset, &g.YOffset,
+				&g.Width, &g.Height, &g.XBearing, &g.YBearing} {
+				*v = float32ToFixed26(fixed26ToFloat32(*v) * scale)
+			}
+		}
+	}
+	return scale, (width - advance*scale) / 2
+}
+
+// Moves bitmap glyphs, like those of color emoji fonts, to their bearings. The
+// renderer draws them from the pen position downwards otherwise, i.e. below
+// the baseline. Picks the bitmaps closest to the pixel size as well.
+func placeBitmaps(run *shaping.Output, pixelSize float32) {
+	ppem := uint16(math.Round(float64(pixelSize)))
+	run.Face.XPpem, run.Face.YPpem = ppem, ppem
+
+	for i := range run.Glyphs {
+		g := &run.Glyphs[i]
+		if _, ok := run.Face.GlyphData(g.GlyphID).(api.GlyphBitmap); ok {
+			g.XOffset += g.XBearing
+			g.YOffset += g.YBearing
+		}
+	}
+}
+
+func fixed26ToFloat32(i fixed.Int26_6) float32 {
+	return float32(i) / (1 << 6)
+}
+
+func float32ToFixed26(f float32) fixed.Int26_6 {
+	return fixed.Int26_6(math.Round(float64(f) * (1 << 6)))
+}
@@ -0,0 +1,71 @@
+package nvim
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"github.com/go-text/typesetting/harfbuzz"
+	"github.com/go-text/typesetting/shaping"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShapeText(t *testing.T) {
+	test.NewApp()
+	defer test.NewApp()
+	shaper.Lock()
+	defer shaper.Unlock()
+
+	// wider than the font's advances, the glyphs still start at their cells
+	font := theme.TextMonospaceFont()
+	run, err := shapeText(font, "a->b", 14, nil)
+	assert.NoError(t, err)
+	if !assert.Len(t, run.Glyphs, 4) {
+		return
+	}
+	snapToCells(&run, 20)
+	x := float32(0)
+	for i, g := range run.Glyphs {
+		assert.Equal(t, i, g.ClusterIndex)
+		assert.InDelta(t, float32(i)*20, x, 1.0/64)
+		x += fixed26ToFloat32(g.XAdvance)
+	}
+
+	liga, err := harfbuzz.ParseFeature("-liga")
+	assert.NoError(t, err)
+	run, err = shapeText(font, "fi", 14, []harfbuzz.Feature{liga})
+	assert.NoError(t, err)
+	assert.Len(t, run.Glyphs, 2)
+}
+
+func TestFitRun(t *testing.T) {
+	test.NewApp()
+	defer test.NewApp()
+	shaper.Lock()
+	defer shaper.Unlock()
+
+	run, err := shapeText(theme.DefaultEmojiFont(), "😀", 14, nil)
+	assert.NoError(t, err)
+	if !assert.Len(t, run.Glyphs, 1) {
+		return
+	}
+	advance := fixed26ToFloat32(run.Glyphs[0].XAdvance)
+
+	// centered within a wider cell
+	wide := run
+	wide.Glyphs = append([]shaping.Glyph(nil), run.Glyphs...)
+	scale, x := fitRun(&wide, advance+10, 100, 100)
+	assert.Equal(t, float32(1), scale)
+	assert.InDelta(t, 5, x, 1.0/64)
+
+	// scaled down to fit a narrower one
+	scale, x = fitRun(&run, advance/2, 100, 100)
+	assert.InDelta(t, 0.5, scale, 1.0/64)
+	assert.InDelta(t, 0, x, 1.0/64)
+	assert.InDelta(t, advance/2, fixed26ToFloat32(run.Glyphs[0].XAdvance), 1.0/64)
+
+	// and to stay within the cell above the baseline
+	top := fixed26ToFloat32(run.Glyphs[0].YBearing)
+	scale, _ = fitRun(&run, 100, top/2, 100)
+	assert.InDelta(t, 0.5, scale, 1.0/64)
+}
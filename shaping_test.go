@@ -0,0 +1,158 @@
+package nvim_test
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+// Returns the text the widget draws itself after shaping it, instead of
+// leaving it to fyne
+func drawnRasters(n *nvim.NeoVim) (rasters []*canvas.Raster) {
+	for _, obj := range test.WidgetRenderer(n).Objects() {
+		if raster, ok := obj.(*canvas.Raster); ok && raster.Visible() {
+			rasters = append(rasters, raster)
+		}
+	}
+	return rasters
+}
+
+func TestRenderShaped(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	var log bytes.Buffer
+	n.Logger = nvim.NewTextLogger(&log, nvim.LogWarn)
+	n.Ligatures = true
+	n.FontFeatures = []string{"ss01", "liga["}
+
+	peer.Redraw(
+		nvimtest.GridResize(10, 2),
+		nvimtest.GridLine(0, 0, nvimtest.Cells("a != b", 0)...),
+		nvimtest.GridCursorGoto(1, 9),
+		nvimtest.Flush(),
+	)
+	assert.Contains(t, log.String(), "invalid font feature")
+
+	assert.Empty(t, drawnRows(n))
+	rasters := drawnRasters(n)
+	if !assert.Len(t, rasters, 1) {
+		return
+	}
+	// trailing spaces are left out of the text, not out of the run
+	cell := cellSize(n)
+	assert.Equal(t, fyne.NewSize(10*cell.Width, cell.Height), rasters[0].Size())
+
+	// something is drawn
+	img := rasters[0].Generator(60, 20).(*image.NRGBA)
+	drawn := false
+	for i := 3; i < len(img.Pix); i += 4 {
+		drawn = drawn || img.Pix[i] > 0
+	}
+	assert.True(t, drawn)
+
+	// switching back draws texts again
+	n.Ligatures, n.FontFeatures = false, nil
+	n.Refresh()
+	assert.Equal(t, map[float32][]string{0: {"a != b"}}, drawnRows(n))
+	assert.Empty(t, drawnRasters(n))
+}
+
+func TestRenderFallbackFonts(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.FallbackFonts = []fyne.Resource{theme.DefaultEmojiFont(), theme.DefaultTextFont()}
+
+	peer.Redraw(
+		nvimtest.GridResize(10, 2),
+		nvimtest.GridLine(0, 0, nvimtest.Cell("Ǆ"), nvimtest.Cell("é"), nvimtest.Cell("x")),
+		nvimtest.GridCursorGoto(1, 9),
+		nvimtest.Flush(),
+	)
+
+	// drawn in the first fallback font which has the glyph, the monospace font
+	// has the others
+	assert.Equal(t, map[float32][]string{0: {"é", "x"}}, drawnRows(n))
+	rasters := drawnRasters(n)
+	if assert.Len(t, rasters, 1) {
+		assert.Equal(t, fyne.NewPos(0, 0), rasters[0].Position())
+	}
+
+	n.FallbackFonts = nil
+	n.Refresh()
+	assert.Equal(t, map[float32][]string{0: {"Ǆ", "é", "x"}}, drawnRows(n))
+	assert.Empty(t, drawnRasters(n))
+}
+
+func TestRenderFallbackFontsRenamed(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	n.FallbackFonts = []fyne.Resource{fyne.NewStaticResource("fallback.ttf", theme.DefaultEmojiFont().Content())}
+
+	peer.Redraw(
+		nvimtest.GridResize(10, 2),
+		nvimtest.GridLine(0, 0, nvimtest.Cell("Ǆ")),
+		nvimtest.GridCursorGoto(1, 9),
+		nvimtest.Flush(),
+	)
+	assert.Empty(t, drawnRasters(n))
+
+	// another font of the same name is a different font
+	n.FallbackFonts = []fyne.Resource{fyne.NewStaticResource("fallback.ttf", theme.DefaultTextFont().Content())}
+	n.Refresh()
+	assert.Empty(t, drawnRows(n))
+	assert.Len(t, drawnRasters(n), 1)
+}
+
+func TestRenderEmoji(t *testing.T) {
+	n, peer := nvimtest.New(t)
+
+	peer.Redraw(
+		nvimtest.GridResize(10, 2),
+		nvimtest.GridLine(0, 0,
+			nvimtest.Cell("😀"), nvimtest.Cell(""), nvimtest.Cell("👍🏽"), nvimtest.Cell(""), nvimtest.Cell("x"),
+			nvimtest.Cell("✅"), nvimtest.Cell(""),
+		),
+		nvimtest.GridCursorGoto(1, 9),
+		nvimtest.Flush(),
+	)
+	cell := cellSize(n)
+
+	// in the emoji font bundled with fyne by default, across both cells, even
+	// those below U+FFFF
+	assert.Equal(t, map[float32][]string{0: {"x"}}, drawnRows(n))
+	rasters := drawnRasters(n)
+	if assert.Len(t, rasters, 3) {
+		assert.Equal(t, fyne.NewSize(2*cell.Width, cell.Height), rasters[0].Size())
+		assert.Equal(t, fyne.NewPos(2*cell.Width, 0), rasters[1].Position())
+		assert.Equal(t, fyne.NewPos(5*cell.Width, 0), rasters[2].Position())
+	}
+
+	// the cursor draws it the same way
+	fyne.CurrentApp().Driver().CanvasForObject(n).Focus(n)
+	peer.Redraw(
+		nvimtest.GridCursorGoto(0, 0),
+		nvimtest.Flush(),
+	)
+	texts, _ := drawnObjects(n)
+	for _, text := range texts {
+		assert.NotEqual(t, "😀", text.Text)
+	}
+	rasters = drawnRasters(n)
+	if assert.Len(t, rasters, 4) {
+		assert.Equal(t, fyne.NewPos(0, 0), rasters[3].Position())
+	}
+
+	// a font without them leaves them to fyne
+	n.EmojiFont = theme.DefaultTextMonospaceFont()
+	peer.Redraw(
+		nvimtest.GridCursorGoto(1, 9),
+		nvimtest.Flush(),
+	)
+	assert.Equal(t, map[float32][]string{0: {"😀", "👍🏽", "x", "✅"}}, drawnRows(n))
+	assert.Empty(t, drawnRasters(n))
+}
@@ -0,0 +1,247 @@
+package nvim
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Snapshot is a copy of what the widget displays, e.g. to compare it against
+// golden files in tests or to export it
+type Snapshot struct {
+	Cells                [][]SnapshotCell
+	CursorRow, CursorCol int
+	CursorShape          string // "block", "horizontal" or "vertical"
+	// The colors of cells without a highlight of their own
+	DefaultFg, DefaultBg color.RGBA
+}
+
+// SnapshotCell describes a single cell with its resolved colors and styles
+type SnapshotCell struct {
+	Text            string
+	Fg, Bg, Special color.RGBA
+
+	Bold, Italic, Strikethrough bool
+	// One of "underline", "undercurl", "underdouble", "underdotted" and
+	// "underdashed" or empty
+	Underline string
+}
+
+// Takes a snapshot of the grid and cursor
+func (n *NeoVim) Snapshot() Snapshot {
+	n.gridMu.Lock()
+	defer n.gridMu.Unlock()
+
+	s := Snapshot{
+		Cells:       make([][]SnapshotCell, len(n.cells)),
+		CursorRow:   n.cursorRow,
+		CursorCol:   n.cursorCol,
+		CursorShape: n.cursorShape(),
+		DefaultFg:   toRGBA(n.defaultHL.Fg),
+		DefaultBg:   toRGBA(n.defaultHL.Bg),
+	}
+
+	for i, row := range n.cells {
+		s.Cells[i] = make([]SnapshotCell, len(row))
+		for j, cell := range row {
+			s.Cells[i][j] = snapshotCell(cell.text, cell.style, n.defaultHL)
+		}
+	}
+
+	return s
+}
+
+// Returns the shape of the cursor in the current mode
+func (n *NeoVim) cursorShape() string {
+	return n.currentModeInfo().CursorShape
+}
+
+// Resolves the colors and styles of a cell in the grid
+func snapshotCell(text string, s *gridStyle, defaults highlight) SnapshotCell {
+	c := SnapshotCell{
+		Text:    text,
+		Fg:      toRGBA(defaults.Fg),
+		Bg:      toRGBA(defaults.Bg),
+		Special: toRGBA(defaults.Special),
+	}
+	if s == nil {
+		return c
+	}
+
+	c.Fg = toRGBA(s.fg)
+	c.Bg = toRGBA(s.bg)
+
+	if s.hl.Special != RGBA_SENTINEL {
+		c.Special = s.hl.Special
+	}
+	c.Bold = s.hl.Bold
+	c.Italic = s.hl.Italic
+	c.Strikethrough = s.hl.Strikethrough
+
+	switch {
+	case s.hl.Underline:
+		c.Underline = "underline"
+	case s.hl.Undercurl:
+		c.Underline = "undercurl"
+	case s.hl.Underdouble:
+		c.Underline = "underdouble"
+	case s.hl.Underdotted:
+		c.Underline = "underdotted"
+	case s.hl.Underdashed:
+		c.Underline = "underdashed"
+	}
+
+	return c
+}
+
+func toRGBA(c color.Color) color.RGBA {
+	if c == nil {
+		return color.RGBA{}
+	}
+
+	r, g, b, a := c.RGBA()
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
+
+// Returns whether two cells are drawn with the same colors and styles
+func (c SnapshotCell) sameStyle(o SnapshotCell) bool {
+	o.Text = c.Text
+	return c == o
+}
+
+// Describes the colors and styles of a cell e.g.
+// "fg=#ffffff bg=#000000 sp=#ff0000 bold,undercurl"
+func (c SnapshotCell) styleString() string {
+	s := fmt.Sprintf("fg=%s bg=%s sp=%s", hexColor(c.Fg), hexColor(c.Bg),
+		hexColor(c.Special))
+
+	var attrs []string
+	if c.Bold {
+		attrs = append(attrs, "bold")
+	}
+	if c.Italic {
+		attrs = append(attrs, "italic")
+	}
+	if c.Strikethrough {
+		attrs = append(attrs, "strikethrough")
+	}
+	if c.Underline != "" {
+		attrs = append(attrs, c.Underline)
+	}
+	if len(attrs) > 0 {
+		s += " " + strings.Join(attrs, ",")
+	}
+
+	return s
+}
+
+func hexColor(c color.RGBA) string {
+	if c.A != 255 {
+		return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// String returns a text dump which is meant to be diffed, e.g.
+//
+//	size 5x2 cursor 0,1 block
+//	|hello|
+//	|     |
+//	0:0-4 fg=#ffffff bg=#000000 sp=#000000
+//	1:0-4 fg=#ffffff bg=#000000 sp=#000000
+//
+// The text of each row is followed by the styles of the cells in each row,
+// with neighbouring cells of equal style joined into a range of columns.
+func (s Snapshot) String() string {
+	var b strings.Builder
+
+	cols := 0
+	if len(s.Cells) > 0 {
+		cols = len(s.Cells[0])
+	}
+	fmt.Fprintf(&b, "size %dx%d cursor %d,%d %s\n", cols, len(s.Cells),
+		s.CursorRow, s.CursorCol, s.CursorShape)
+
+	for _, row := range s.Cells {
+		b.WriteByte('|')
+		for _, c := range row {
+			b.WriteString(c.Text)
+		}
+		b.WriteString("|\n")
+	}
+
+	for i, row := range s.Cells {
+		for start := 0; start < len(row); {
+			end := start
+			for end+1 < len(row) && row[end+1].sameStyle(row[start]) {
+				end++
+			}
+			fmt.Fprintf(&b, "%d:%d-%d %s\n", i, start, end, row[start].styleString())
+			start = end + 1
+		}
+	}
+
+	return b.String()
+}
+
+// ANSI returns the grid as text with ANSI escape sequences for 24-bit colors
+// and styles, one line per row. The cursor is not included.
+func (s Snapshot) ANSI() string {
+	var b strings.Builder
+
+	for _, row := range s.Cells {
+		b.WriteString(rowANSI(row))
+		b.WriteString("\x1b[0m\n")
+	}
+
+	return b.String()
+}
+
+// Returns the text of a row with the escape sequences for its styles
+func rowANSI(row []SnapshotCell) string {
+	var b strings.Builder
+
+	for j, c := range row {
+		if j == 0 || !c.sameStyle(row[j-1]) {
+			b.WriteString(c.sgr())
+		}
+		b.WriteString(c.Text)
+	}
+
+	return b.String()
+}
+
+// Returns the escape sequence which sets all colors and styles of the cell
+func (c SnapshotCell) sgr() string {
+	var b strings.Builder
+	b.WriteString("\x1b[0")
+	if c.Bold {
+		b.WriteString(";1")
+	}
+	if c.Italic {
+		b.WriteString(";3")
+	}
+	switch c.Underline {
+	case "underline":
+		b.WriteString(";4")
+	case "underdouble":
+		b.WriteString(";4:2")
+	case "undercurl":
+		b.WriteString(";4:3")
+	case "underdotted":
+		b.WriteString(";4:4")
+	case "underdashed":
+		b.WriteString(";4:5")
+	}
+	if c.Strikethrough {
+		b.WriteString(";9")
+	}
+	fmt.Fprintf(&b, ";38;2;%d;%d;%d", c.Fg.R, c.Fg.G, c.Fg.B)
+	fmt.Fprintf(&b, ";48;2;%d;%d;%d", c.Bg.R, c.Bg.G, c.Bg.B)
+	if c.Underline != "" {
+		fmt.Fprintf(&b, ";58;2;%d;%d;%d", c.Special.R, c.Special.G, c.Special.B)
+	}
+	b.WriteByte('m')
+
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package nvim_test
+
+import (
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"github.com/stretchr/testify/assert"
+	nvim "github.com/yesoer/fyne-nvim"
+	"github.com/yesoer/fyne-nvim/nvimtest"
+)
+
+// Draws a small screen using a few highlights and moves the cursor around
+func drawScene(peer *nvimtest.Peer) {
+	peer.Redraw(
+		nvimtest.DefaultColorsSet(white, black, red),
+		nvimtest.ModeInfoSet(
+			map[string]interface{}{"name": "normal", "cursor_shape": "block", "cell_percentage": 0},
+			map[string]interface{}{"name": "insert", "cursor_shape": "vertical", "cell_percentage": 25},
+		),
+		nvimtest.ModeChange("normal", 0),
+		nvimtest.GridResize(16, 4),
+		nvimtest.HLAttrDefine(1, map[string]interface{}{"foreground": red, "bold": true}),
+		nvimtest.HLAttrDefine(2, map[string]interface{}{"background": green, "undercurl": true, "special": red}),
+		nvimtest.HLAttrDefine(3, map[string]interface{}{"reverse": true, "foreground": green, "background": black}),
+		nvimtest.GridLine(0, 0, append(nvimtest.Cells("fun", 1), nvimtest.Cells("c main", 0)...)...),
+		nvimtest.GridLine(1, 2, nvimtest.Cells("typo", 2)...),
+		nvimtest.GridLine(3, 0, append(nvimtest.Cells("-- INSERT --", 3), nvimtest.Cell(" ", 3, 4))...),
+		nvimtest.GridCursorGoto(1, 4),
+		nvimtest.ModeChange("insert", 1),
+		nvimtest.Flush(),
+	)
+}
+
+func TestSnapshot(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	drawScene(peer)
+
+	s := n.Snapshot()
+	assert.Equal(t, 1, s.CursorRow)
+	assert.Equal(t, 4, s.CursorCol)
+	assert.Equal(t, "vertical", s.CursorShape)
+	assert.True(t, s.Cells[0][0].Bold)
+	assert.Equal(t, "undercurl", s.Cells[1][2].Underline)
+
+	nvimtest.AssertSnapshot(t, n, "scene.golden")
+
+	// with the focus, so the cursor isn't hollow
+	fyne.CurrentApp().Driver().CanvasForObject(n).Focus(n)
+	nvimtest.AssertImage(t, n, "scene.png")
+}
+
+func TestSnapshotScroll(t *testing.T) {
+	n, peer := nvimtest.New(t)
+	drawScene(peer)
+
+	peer.Redraw(
+		nvimtest.GridScroll(0, 3, 0, 16, 1),
+		nvimtest.GridLine(2, 0, nvimtest.Cells("new", 0)...),
+		nvimtest.GridCursorGoto(2, 3),
+		nvimtest.Flush(),
+	)
+
+	nvimtest.AssertSnapshot(t, n, "scene_scrolled.golden")
+}
+
+func TestSnapshotANSI(t *testing.T) {
+	n := nvim.NewUnattached()
+	n.HandleNvimEvent(nvimtest.GridResize(3, 1))
+	n.HandleNvimEvent(nvimtest.GridLine(0, 0, nvimtest.Cell("a", 0), nvimtest.Cell("b"),
+		nvimtest.Cell("c")))
+
+	ansi := n.Snapshot().ANSI()
+	assert.True(t, strings.HasPrefix(ansi, "\x1b[0;38;2;"), "starts with the style: %q", ansi)
+	assert.True(t, strings.HasSuffix(ansi, "abc\x1b[0m\n"), "ends with the text: %q", ansi)
+}